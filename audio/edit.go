@@ -0,0 +1,218 @@
+package audio
+
+import "time"
+
+// QueueEdit changes a queue that Queue.Edit holds locked. Its methods do
+// what Queue's methods of the same name do, without taking the lock, so
+// several changes, and whatever is read to decide on them, happen as one.
+type QueueEdit struct {
+	q *Queue
+}
+
+// Edit runs change with the queue locked, then publishes QueueChanged.
+// change must not call the queue's own methods.
+func (q *Queue) Edit(change func(e *QueueEdit)) {
+	defer q.changed()
+	q.Lock()
+	defer q.Unlock()
+	change(&QueueEdit{q: q})
+}
+
+// Tracks returns the queued tracks.
+func (e *QueueEdit) Tracks() []*Track {
+	return append([]*Track(nil), e.q.Tracks...)
+}
+
+// Current returns the track being played, if any.
+func (e *QueueEdit) Current() *Track {
+	return e.q.current
+}
+
+func (e *QueueEdit) LoopMode() LoopMode {
+	return e.q.LoopMode
+}
+
+func (e *QueueEdit) EnqueueMultiple(tracks []*Track) {
+	q := e.q
+	switch {
+	case q.fair:
+		for _, t := range tracks {
+			q.insertFair(t)
+		}
+	case q.shuffle:
+		for _, t := range tracks {
+			i := q.random().Intn(len(q.Tracks) + 1)
+			q.Tracks = append(q.Tracks[:i], append([]*Track{t}, q.Tracks[i:]...)...)
+		}
+	default:
+		q.Tracks = append(q.Tracks, tracks...)
+	}
+}
+
+func (e *QueueEdit) PushFront(tracks ...*Track) {
+	e.q.Tracks = append(append([]*Track(nil), tracks...), e.q.Tracks...)
+}
+
+func (e *QueueEdit) Requeue(offset time.Duration) {
+	q := e.q
+	if q.current == nil {
+		return
+	}
+	// LoopAll has already re-queued it at the end.
+	if n := len(q.Tracks); q.LoopMode == LoopAll && n > 0 && q.Tracks[n-1] == q.current {
+		q.Tracks = q.Tracks[:n-1]
+	}
+
+	track := *q.current
+	track.Offset = offset
+	q.Tracks = append([]*Track{&track}, q.Tracks...)
+	q.current = nil
+}
+
+func (e *QueueEdit) Clear() {
+	e.q.Tracks = nil
+	e.q.current = nil
+}
+
+func (e *QueueEdit) Shuffle() {
+	q := e.q
+	q.random().Shuffle(len(q.Tracks), func(i, j int) {
+		q.Tracks[i], q.Tracks[j] = q.Tracks[j], q.Tracks[i]
+	})
+}
+
+func (e *QueueEdit) SmartShuffle() {
+	q := e.q
+	q.Tracks = spread(q.Tracks, q.random(),
+		func(t *Track) string { return t.Uploader },
+		func(t *Track) string { return t.RequesterID },
+	)
+}
+
+func (e *QueueEdit) Replace(old, t *Track) {
+	q := e.q
+	if q.current == old {
+		q.current = t
+	}
+	for i, other := range q.Tracks {
+		if other == old {
+			q.Tracks[i] = t
+		}
+	}
+}
+
+func (e *QueueEdit) Remove(index int) bool {
+	q := e.q
+	if index < 0 || index >= len(q.Tracks) {
+		return false
+	}
+	q.Tracks = append(q.Tracks[:index], q.Tracks[index+1:]...)
+	return true
+}
+
+func (e *QueueEdit) Insert(index int, track *Track) bool {
+	q := e.q
+	if index < 0 || index > len(q.Tracks) {
+		return false
+	}
+	q.Tracks = append(q.Tracks[:index], append([]*Track{track}, q.Tracks[index:]...)...)
+	return true
+}
+
+func (e *QueueEdit) Move(from, to int) bool {
+	q := e.q
+	if from < 0 || from >= len(q.Tracks) || to < 0 || to >= len(q.Tracks) {
+		return false
+	}
+
+	track := q.Tracks[from]
+	// Remove from old position
+	q.Tracks = append(q.Tracks[:from], q.Tracks[from+1:]...)
+	// Insert at new position
+	q.Tracks = append(q.Tracks[:to], append([]*Track{track}, q.Tracks[to:]...)...)
+
+	return true
+}
+
+func (e *QueueEdit) RemoveRange(from, to int) int {
+	return e.RemoveRangeWhere(from, to, func(*Track) bool { return true })
+}
+
+func (e *QueueEdit) RemoveRangeWhere(from, to int, match func(t *Track) bool) int {
+	q := e.q
+	if from < 0 || to >= len(q.Tracks) || from > to {
+		return 0
+	}
+	kept := append([]*Track(nil), q.Tracks[:from]...)
+	removed := 0
+	for _, t := range q.Tracks[from : to+1] {
+		if match(t) {
+			removed++
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	q.Tracks = append(kept, q.Tracks[to+1:]...)
+	return removed
+}
+
+func (e *QueueEdit) RemoveWhere(match func(t *Track) bool) int {
+	q := e.q
+	kept := q.Tracks[:0]
+	for _, t := range q.Tracks {
+		if !match(t) {
+			kept = append(kept, t)
+		}
+	}
+	removed := len(q.Tracks) - len(kept)
+	clear(q.Tracks[len(kept):])
+	q.Tracks = kept
+	return removed
+}
+
+func (e *QueueEdit) Dedupe() int {
+	return e.DedupeWhere(func(*Track) bool { return true })
+}
+
+func (e *QueueEdit) DedupeWhere(match func(t *Track) bool) int {
+	q := e.q
+	seen := make(map[string]bool)
+	if q.current != nil && q.LoopMode != LoopAll {
+		seen[q.current.Key()] = true
+	}
+	kept := q.Tracks[:0]
+	for _, t := range q.Tracks {
+		if !seen[t.Key()] || !match(t) {
+			seen[t.Key()] = true
+			kept = append(kept, t)
+		}
+	}
+	removed := len(q.Tracks) - len(kept)
+	clear(q.Tracks[len(kept):])
+	q.Tracks = kept
+	return removed
+}
+
+func (e *QueueEdit) Find(text string) []int {
+	var positions []int
+	for i, t := range e.q.Tracks {
+		if t.Matches(text) {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+func (e *QueueEdit) DropBefore(index int) bool {
+	q := e.q
+	if index < 0 || index >= len(q.Tracks) {
+		return false
+	}
+	q.Tracks = append([]*Track(nil), q.Tracks[index:]...)
+	return true
+}
+
+func (e *QueueEdit) SetState(tracks []*Track, mode LoopMode) {
+	e.q.Tracks = append([]*Track(nil), tracks...)
+	e.q.LoopMode = mode
+}
@@ -0,0 +1,138 @@
+package audio
+
+import (
+	"sync"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+const (
+	DEFAULT_BITRATE = 64000
+	MIN_BITRATE     = 8000
+	MAX_BITRATE     = 384000
+)
+
+type EncoderSettings struct {
+	Bitrate    int  // bits per second
+	Complexity int  // 0-10
+	FEC        bool // in-band forward error correction
+	PacketLoss int  // expected packet loss, percent
+}
+
+// EncoderSettingsForBitrate derives encoder settings from a voice channel's
+// configured bitrate. Low bitrate channels expect more packet loss, and so
+// get more FEC, since they are usually picked for poor connections.
+func EncoderSettingsForBitrate(channelBitrate int) EncoderSettings {
+	if channelBitrate <= 0 {
+		channelBitrate = DEFAULT_BITRATE
+	}
+	channelBitrate = min(max(channelBitrate, MIN_BITRATE), MAX_BITRATE)
+
+	s := EncoderSettings{
+		Bitrate:    channelBitrate,
+		Complexity: 10,
+		FEC:        true,
+		PacketLoss: 5,
+	}
+	if channelBitrate <= DEFAULT_BITRATE {
+		s.PacketLoss = 10
+	}
+	return s
+}
+
+// Encoder is a guild's Opus encoder, reused by every track of the session.
+type Encoder struct {
+	mu       sync.Mutex
+	opus     *opus.Encoder
+	settings EncoderSettings
+}
+
+func NewEncoder(s EncoderSettings) (*Encoder, error) {
+	enc, err := opus.NewEncoder(FRAME_RATE, CHANNELS, opus.AppAudio)
+	if err != nil {
+		return nil, err
+	}
+	e := &Encoder{opus: enc}
+	if err := e.Configure(s); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Configure applies s to the encoder.
+func (e *Encoder) Configure(s EncoderSettings) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, err := range []error{
+		e.opus.SetBitrate(s.Bitrate),
+		e.opus.SetComplexity(s.Complexity),
+		e.opus.SetInBandFEC(s.FEC),
+		e.opus.SetPacketLossPerc(s.PacketLoss),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	e.settings = s
+	return nil
+}
+
+func (e *Encoder) Settings() EncoderSettings {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.settings
+}
+
+func (e *Encoder) Encode(pcm []int16) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	data := make([]byte, MAX_BYTES)
+	n, err := e.opus.Encode(pcm, data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+type EncoderManager struct {
+	encoders map[string]*Encoder // guildID → Encoder
+	sync.Mutex
+}
+
+func NewEncoderManager() *EncoderManager {
+	return &EncoderManager{
+		encoders: make(map[string]*Encoder),
+	}
+}
+
+// Get returns the guild's encoder, creating it on first use and
+// reconfiguring it when the settings changed since the last track.
+func (em *EncoderManager) Get(guildID string, s EncoderSettings) (*Encoder, error) {
+	em.Lock()
+	defer em.Unlock()
+
+	if enc, ok := em.encoders[guildID]; ok {
+		if enc.Settings() != s {
+			if err := enc.Configure(s); err != nil {
+				return nil, err
+			}
+		}
+		return enc, nil
+	}
+
+	enc, err := NewEncoder(s)
+	if err != nil {
+		return nil, err
+	}
+	em.encoders[guildID] = enc
+	return enc, nil
+}
+
+// Release drops the guild's encoder when its voice session ends.
+func (em *EncoderManager) Release(guildID string) {
+	em.Lock()
+	defer em.Unlock()
+	delete(em.encoders, guildID)
+}
@@ -0,0 +1,60 @@
+package audio
+
+import "testing"
+
+func TestEncoderSettingsForBitrate(t *testing.T) {
+	for _, tt := range []struct {
+		channel int
+		want    EncoderSettings
+	}{
+		{0, EncoderSettings{Bitrate: DEFAULT_BITRATE, Complexity: 10, FEC: true, PacketLoss: 10}},
+		{1000, EncoderSettings{Bitrate: MIN_BITRATE, Complexity: 10, FEC: true, PacketLoss: 10}},
+		{96000, EncoderSettings{Bitrate: 96000, Complexity: 10, FEC: true, PacketLoss: 5}},
+		{512000, EncoderSettings{Bitrate: MAX_BITRATE, Complexity: 10, FEC: true, PacketLoss: 5}},
+	} {
+		if got := EncoderSettingsForBitrate(tt.channel); got != tt.want {
+			t.Errorf("EncoderSettingsForBitrate(%d) = %+v, want %+v", tt.channel, got, tt.want)
+		}
+	}
+}
+
+// TestEncoderConfigure reads the settings back from libopus, so they
+// are known to reach the encoder rather than only being stored.
+func TestEncoderConfigure(t *testing.T) {
+	want := EncoderSettings{Bitrate: 96000, Complexity: 4, FEC: true, PacketLoss: 20}
+	e, err := NewEncoder(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(want EncoderSettings) {
+		t.Helper()
+		var got EncoderSettings
+		var errs [4]error
+		got.Bitrate, errs[0] = e.opus.Bitrate()
+		got.Complexity, errs[1] = e.opus.Complexity()
+		got.FEC, errs[2] = e.opus.InBandFEC()
+		got.PacketLoss, errs[3] = e.opus.PacketLossPerc()
+		for _, err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got != want || e.Settings() != want {
+			t.Errorf("encoder has %+v (settings %+v), want %+v", got, e.Settings(), want)
+		}
+	}
+	check(want)
+
+	want = EncoderSettings{Bitrate: 32000, Complexity: 10, PacketLoss: 0}
+	if err := e.Configure(want); err != nil {
+		t.Fatal(err)
+	}
+	check(want)
+
+	if err := e.Configure(EncoderSettings{Bitrate: 32000, Complexity: 11}); err == nil {
+		t.Error("complexity 11 was accepted")
+	}
+	if _, err := e.Encode(make([]int16, FRAME_SIZE*CHANNELS)); err != nil {
+		t.Errorf("encoding silence: %v", err)
+	}
+}
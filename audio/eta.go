@@ -0,0 +1,71 @@
+package audio
+
+import "time"
+
+// Estimate is how long until a track starts playing. It is unknown when a
+// track ahead of it has no known length, such as a live stream or a track
+// whose metadata hasn't been resolved yet.
+type Estimate struct {
+	In    time.Duration
+	Known bool
+}
+
+// Timeline is the queue's playback schedule from now on.
+type Timeline struct {
+	Starts    []Estimate    // when each queued track starts, by queue index, less LoopAll's copy of the current track
+	Remaining time.Duration // total known playback left, including the current track
+	Unknown   int           // tracks left out of Remaining because their length is unknown
+}
+
+// knownLength is t's length left to play from offset, if t has a length.
+// Live streams often report no length or 0:00.
+func knownLength(t *Track, offset time.Duration) (time.Duration, bool) {
+	length, ok := t.Length()
+	if !ok || length <= 0 {
+		return 0, false
+	}
+	return max(0, length-offset), true
+}
+
+// Timeline estimates when each queued track starts and how much playback
+// is left, given how far into the current track playback is. With LoopOne
+// the current track repeats indefinitely, so nothing queued has a start.
+// With LoopAll the queue ends in the current track again, which is left
+// out, so it isn't counted twice.
+func (q *Queue) Timeline(elapsed time.Duration) Timeline {
+	q.Lock()
+	defer q.Unlock()
+
+	tracks := q.Tracks
+	if n := len(tracks); q.current != nil && q.LoopMode == LoopAll && n > 0 && tracks[n-1] == q.current {
+		tracks = tracks[:n-1]
+	}
+
+	var tl Timeline
+	at, known := time.Duration(0), true
+	if q.current != nil {
+		if left, ok := knownLength(q.current, elapsed); ok {
+			tl.Remaining += left
+			at += left
+		} else {
+			tl.Unknown++
+			known = false
+		}
+		if q.LoopMode == LoopOne {
+			known = false
+		}
+	}
+
+	tl.Starts = make([]Estimate, len(tracks))
+	for i, t := range tracks {
+		tl.Starts[i] = Estimate{In: at, Known: known}
+		if left, ok := knownLength(t, t.Offset); ok {
+			tl.Remaining += left
+			at += left
+		} else {
+			tl.Unknown++
+			known = false
+		}
+	}
+	return tl
+}
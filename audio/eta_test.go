@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// queueOf returns a queue playing a track of length current, with tracks
+// of the given lengths queued after it. "" is an unknown length.
+func queueOf(current string, queued ...string) *Queue {
+	q := NewQueueManager(nil).Get("guild")
+	for _, length := range append([]string{current}, queued...) {
+		q.Enqueue(&Track{URL: "https://example.com/" + length, Duration: length})
+	}
+	q.Dequeue()
+	return q
+}
+
+func TestTimeline(t *testing.T) {
+	known := func(in time.Duration) Estimate { return Estimate{In: in, Known: true} }
+	unknown := func(in time.Duration) Estimate { return Estimate{In: in} }
+
+	for _, tt := range []struct {
+		name    string
+		queue   *Queue
+		elapsed time.Duration
+		want    Timeline
+	}{
+		{
+			name:    "lengths add up",
+			queue:   queueOf("3:00", "1:00", "2:30", "10"),
+			elapsed: time.Minute,
+			want: Timeline{
+				Starts:    []Estimate{known(2 * time.Minute), known(3 * time.Minute), known(5*time.Minute + 30*time.Second)},
+				Remaining: 5*time.Minute + 40*time.Second,
+			},
+		},
+		{
+			name:    "past the end of the current track",
+			queue:   queueOf("1:00", "1:00"),
+			elapsed: 2 * time.Minute,
+			want:    Timeline{Starts: []Estimate{known(0)}, Remaining: time.Minute},
+		},
+		{
+			name:  "an unknown length hides later starts",
+			queue: queueOf("1:00", "1:00", "", "0:00", "2:00"),
+			want: Timeline{
+				Starts:    []Estimate{known(time.Minute), known(2 * time.Minute), unknown(2 * time.Minute), unknown(2 * time.Minute)},
+				Remaining: 4 * time.Minute,
+				Unknown:   2,
+			},
+		},
+		{
+			name:  "a live current track",
+			queue: queueOf("", "1:00"),
+			want:  Timeline{Starts: []Estimate{unknown(0)}, Remaining: time.Minute, Unknown: 1},
+		},
+		{
+			name:  "nothing playing",
+			queue: NewQueueManager(nil).Get("guild"),
+			want:  Timeline{Starts: []Estimate{}},
+		},
+	} {
+		if got := tt.queue.Timeline(tt.elapsed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTimelineOffsets(t *testing.T) {
+	q := queueOf("1:00", "3:00", "1:00")
+	// A track stopped partway plays from where it was stopped.
+	q.List()[0].Offset = 2 * time.Minute
+
+	tl := q.Timeline(0)
+	if tl.Starts[1].In != 2*time.Minute || tl.Remaining != 3*time.Minute {
+		t.Errorf("got %+v, want the second track 2m in and 3m left", tl)
+	}
+}
+
+func TestTimelineLoopOne(t *testing.T) {
+	q := queueOf("1:00", "1:00")
+	q.SetLoopMode(LoopOne)
+
+	tl := q.Timeline(0)
+	if tl.Starts[0].Known {
+		t.Errorf("with loop one, the next track starts %+v", tl.Starts[0])
+	}
+}
+
+func TestTimelineLoopAll(t *testing.T) {
+	q := NewQueueManager(nil).Get("guild")
+	q.SetLoopMode(LoopAll)
+	q.EnqueueMultiple([]*Track{{URL: "https://example.com/a", Duration: "1:00"}, {URL: "https://example.com/b", Duration: "2:00"}})
+	// Dequeuing puts the current track back at the end of the queue.
+	q.Dequeue()
+
+	tl := q.Timeline(0)
+	if len(tl.Starts) != 1 || tl.Remaining != 3*time.Minute {
+		t.Errorf("with loop all, timeline = %+v, want one start and 3m remaining", tl)
+	}
+}
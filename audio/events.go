@@ -0,0 +1,189 @@
+package audio
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is anything published on the EventBus. Every event belongs to a guild.
+type Event interface {
+	Guild() string
+}
+
+type EndReason int
+
+const (
+	EndFinished EndReason = iota
+	EndSkipped
+	EndStopped
+	EndError
+	EndInterrupted // replaced by another track and re-queued
+)
+
+func (r EndReason) String() string {
+	switch r {
+	case EndFinished:
+		return "finished"
+	case EndSkipped:
+		return "skipped"
+	case EndStopped:
+		return "stopped"
+	case EndError:
+		return "error"
+	case EndInterrupted:
+		return "interrupted"
+	default:
+		return "unknown"
+	}
+}
+
+type TrackStarted struct {
+	GuildID string
+	Track   *Track
+}
+
+type TrackEnded struct {
+	GuildID string
+	Track   *Track
+	Reason  EndReason
+	Err     error         // set when Reason is EndError
+	Played  time.Duration // how long it actually played
+}
+
+// QueueChanged is published after any mutation of a guild's queue.
+type QueueChanged struct {
+	GuildID string
+	Length  int
+}
+
+type Paused struct {
+	GuildID string
+	Track   *Track
+}
+
+type Resumed struct {
+	GuildID string
+	Track   *Track
+}
+
+// StateChanged is published on every player state transition.
+type StateChanged struct {
+	GuildID string
+	From    PlayerState
+	To      PlayerState
+}
+
+type VoiceJoined struct {
+	GuildID   string
+	ChannelID string
+}
+
+type VoiceLeft struct {
+	GuildID string
+}
+
+func (e TrackStarted) Guild() string { return e.GuildID }
+func (e TrackEnded) Guild() string   { return e.GuildID }
+func (e QueueChanged) Guild() string { return e.GuildID }
+func (e Paused) Guild() string       { return e.GuildID }
+func (e Resumed) Guild() string      { return e.GuildID }
+func (e StateChanged) Guild() string { return e.GuildID }
+func (e VoiceJoined) Guild() string  { return e.GuildID }
+func (e VoiceLeft) Guild() string    { return e.GuildID }
+
+// EventBus fans events out to subscribers. Each subscriber receives events
+// in publish order on its own goroutine, so publishers never block on
+// slow subscribers and subscribers may call back into the player.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []*subscriber
+}
+
+type subscriber struct {
+	mu      sync.Mutex
+	pending []Event
+	wake    chan struct{}
+	done    chan struct{}
+	handle  func(Event)
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Publish delivers ev to every subscriber. A nil bus discards events.
+func (b *EventBus) Publish(ev Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, s := range b.subscribers {
+		s.mu.Lock()
+		s.pending = append(s.pending, ev)
+		s.mu.Unlock()
+
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SubscribeAll calls fn for every event. The returned func unsubscribes.
+func (b *EventBus) SubscribeAll(fn func(Event)) func() {
+	s := &subscriber{
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		handle: fn,
+	}
+	go s.run()
+
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, s)
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			for i, other := range b.subscribers {
+				if other == s {
+					b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+					break
+				}
+			}
+			b.mu.Unlock()
+			close(s.done)
+		})
+	}
+}
+
+// Subscribe calls fn for every event of type E.
+func Subscribe[E Event](b *EventBus, fn func(E)) func() {
+	return b.SubscribeAll(func(ev Event) {
+		if e, ok := ev.(E); ok {
+			fn(e)
+		}
+	})
+}
+
+func (s *subscriber) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+		}
+
+		s.mu.Lock()
+		events := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+
+		for _, ev := range events {
+			s.handle(ev)
+		}
+	}
+}
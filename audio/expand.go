@@ -0,0 +1,172 @@
+package audio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// PlaylistOptions picks which entries of a playlist to expand.
+type PlaylistOptions struct {
+	Start   int  // first entry, counted from 1; 0 means the first
+	End     int  // last entry, inclusive; 0 means the last
+	Limit   int  // stop after this many entries; 0 means no limit
+	Shuffle bool // list the chosen entries in random order
+}
+
+// IsPlaylistURL reports whether rawURL names a playlist rather than one
+// track: YouTube and YouTube Music playlists, mixes and watch URLs with a
+// list= parameter, SoundCloud sets and Bandcamp albums.
+func IsPlaylistURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+
+	switch {
+	case u.Query().Get("list") != "":
+		return true
+	case host == "music.youtube.com":
+		// Playlists and albums opened from the library.
+		return strings.HasPrefix(u.Path, "/browse/VL") || strings.HasPrefix(u.Path, "/browse/MPREb")
+	case host == "soundcloud.com" || strings.HasSuffix(host, ".soundcloud.com"):
+		return strings.Contains(u.Path, "/sets/")
+	case strings.HasSuffix(host, ".bandcamp.com"):
+		return strings.HasPrefix(u.Path, "/album/")
+	}
+	return false
+}
+
+// ErrPastLimit is what StreamPlaylistTracks yields when the playlist has
+// more entries than its limit let through.
+var ErrPastLimit = errors.New("the playlist goes on past the limit")
+
+// StreamPlaylistTracks lists a playlist's tracks as yt-dlp finds them, with
+// whatever metadata it returns without visiting each track, so the first
+// can play while the rest load. The tracks channel is closed when the
+// listing ends or stop is closed, and errs then yields one error or nil.
+func StreamPlaylistTracks(playlistURL string, opts PlaylistOptions, stop <-chan struct{}) (<-chan *Track, <-chan error) {
+	tracks := make(chan *Track)
+	errs := make(chan error, 1)
+
+	args := []string{"--flat-playlist", "--yes-playlist", "--print", "%(url)s|%(duration_string)s|%(uploader)s|%(title)s"}
+	if opts.Start > 0 || opts.End > 0 {
+		items := ":"
+		if opts.Start > 0 {
+			items = fmt.Sprint(opts.Start) + items
+		}
+		if opts.End > 0 {
+			items += fmt.Sprint(opts.End)
+		}
+		args = append(args, "--playlist-items", items)
+	}
+	if opts.Shuffle {
+		args = append(args, "--playlist-random")
+	}
+	cmd := exec.Command("yt-dlp", append(args, playlistURL)...)
+
+	go func() {
+		defer close(tracks)
+
+		out, err := cmd.StdoutPipe()
+		if err == nil {
+			err = cmd.Start()
+		}
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		// Stopping or reaching the limit kills yt-dlp rather than waiting
+		// for the rest of a huge playlist to be listed.
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-stop:
+				cmd.Process.Kill()
+			case <-done:
+			}
+		}()
+
+		sent, stopped, more := 0, false, false
+		scanner := bufio.NewScanner(out)
+		for !stopped && !more && scanner.Scan() {
+			track := parsePlaylistEntry(scanner.Text())
+			if track == nil {
+				continue
+			}
+			if opts.Limit > 0 && sent == opts.Limit {
+				// Only an entry past the limit shows the limit cut it short.
+				more = true
+				break
+			}
+			select {
+			case tracks <- track:
+				sent++
+			case <-stop:
+				stopped = true
+			}
+		}
+		close(done)
+		select {
+		case <-stop:
+			stopped = true
+		default:
+		}
+
+		if stopped || more {
+			cmd.Process.Kill()
+			cmd.Wait()
+			if more {
+				errs <- ErrPastLimit
+			} else {
+				errs <- nil
+			}
+			return
+		}
+		errs <- cmd.Wait()
+	}()
+	return tracks, errs
+}
+
+// ExtractPlaylistTracks lists the chosen entries of a playlist at once,
+// up to the limit.
+func ExtractPlaylistTracks(playlistURL string, opts PlaylistOptions) ([]*Track, error) {
+	stream, errs := StreamPlaylistTracks(playlistURL, opts, nil)
+	var tracks []*Track
+	for track := range stream {
+		tracks = append(tracks, track)
+	}
+	if err := <-errs; err != ErrPastLimit {
+		return tracks, err
+	}
+	return tracks, nil
+}
+
+func parsePlaylistEntry(line string) *Track {
+	parts := strings.SplitN(line, "|", 4)
+	if len(parts) != 4 {
+		return nil
+	}
+	url, duration, uploader, title := parts[0], parts[1], parts[2], parts[3]
+	// Fix: Only prepend if not already a full URL
+	if !strings.HasPrefix(url, "http") {
+		url = "https://www.youtube.com/watch?v=" + url
+	}
+	track := &Track{
+		Title: title,
+		URL:   url,
+	}
+	// yt-dlp prints NA for each field a flat listing doesn't have.
+	if duration != "NA" {
+		track.Duration = duration
+	}
+	if uploader != "NA" {
+		track.Uploader = uploader
+	}
+	return track
+}
@@ -0,0 +1,20 @@
+package audio
+
+import "testing"
+
+func TestParsePlaylistEntry(t *testing.T) {
+	for _, tt := range []struct {
+		line string
+		want *Track
+	}{
+		{"abc|3:00|Uploader|Title", &Track{URL: "https://www.youtube.com/watch?v=abc", Duration: "3:00", Uploader: "Uploader", Title: "Title"}},
+		{"https://youtu.be/abc|NA|Uploader|Title", &Track{URL: "https://youtu.be/abc", Uploader: "Uploader", Title: "Title"}},
+		{"https://youtu.be/abc|3:00|NA|Title|with a bar", &Track{URL: "https://youtu.be/abc", Duration: "3:00", Title: "Title|with a bar"}},
+		{"abc|3:00", nil},
+	} {
+		got := parsePlaylistEntry(tt.line)
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("parsePlaylistEntry(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
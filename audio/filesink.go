@@ -0,0 +1,242 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// NewFileSink picks a WAV or Ogg Opus sink from the file extension.
+func NewFileSink(path string) (AudioSink, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav":
+		return NewWAVSink(path)
+	case ".ogg", ".opus":
+		return NewOggSink(path)
+	default:
+		return nil, fmt.Errorf("unsupported sink file type: %s", path)
+	}
+}
+
+const wavHeaderSize = 44
+
+// WAVSink writes raw PCM frames to a 16-bit stereo WAV file.
+type WAVSink struct {
+	mu       sync.Mutex
+	file     *os.File
+	dataSize uint32
+	closed   bool
+}
+
+func NewWAVSink(path string) (*WAVSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &WAVSink{file: file}
+	// Sizes are patched in on Close.
+	if err := s.writeHeader(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *WAVSink) writeHeader() error {
+	const bitsPerSample = 16
+	blockAlign := CHANNELS * bitsPerSample / 8
+
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], 36+s.dataSize)
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], CHANNELS)
+	binary.LittleEndian.PutUint32(header[24:], FRAME_RATE)
+	binary.LittleEndian.PutUint32(header[28:], uint32(FRAME_RATE*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:], bitsPerSample)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], s.dataSize)
+
+	_, err := s.file.WriteAt(header, 0)
+	return err
+}
+
+func (s *WAVSink) Speaking(bool) error { return nil }
+
+func (s *WAVSink) WriteOpus([]byte) error {
+	return errors.New("wav sink only accepts PCM")
+}
+
+func (s *WAVSink) WritePCM(frame []int16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+
+	buf := make([]byte, len(frame)*2)
+	for i, sample := range frame {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(sample))
+	}
+	if _, err := s.file.WriteAt(buf, int64(wavHeaderSize+s.dataSize)); err != nil {
+		return err
+	}
+	s.dataSize += uint32(len(buf))
+	return nil
+}
+
+func (s *WAVSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if err := s.writeHeader(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+const (
+	oggPreSkip = 312
+	oggSerial  = 0x4d424f54
+)
+
+// OggSink muxes Opus packets into an Ogg Opus file, one packet per page.
+type OggSink struct {
+	mu       sync.Mutex
+	file     *os.File
+	sequence uint32
+	granule  uint64
+	pending  []byte // held back so the final page can carry end-of-stream
+	closed   bool
+}
+
+func NewOggSink(path string) (*OggSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &OggSink{file: file}
+
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1 // version
+	head[9] = CHANNELS
+	binary.LittleEndian.PutUint16(head[10:], oggPreSkip)
+	binary.LittleEndian.PutUint32(head[12:], FRAME_RATE)
+
+	vendor := "musicbot"
+	tags := make([]byte, 8+4+len(vendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(vendor)))
+	copy(tags[12:], vendor)
+
+	if err := s.writePage(head, 0x02, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := s.writePage(tags, 0, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *OggSink) Speaking(bool) error { return nil }
+
+func (s *OggSink) WriteOpus(packet []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+
+	if s.pending != nil {
+		s.granule += FRAME_SIZE
+		if err := s.writePage(s.pending, 0, s.granule); err != nil {
+			return err
+		}
+	}
+	s.pending = append([]byte(nil), packet...)
+	return nil
+}
+
+func (s *OggSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if s.pending != nil {
+		s.granule += FRAME_SIZE
+	}
+	if err := s.writePage(s.pending, 0x04, s.granule); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+func (s *OggSink) writePage(packet []byte, headerType byte, granule uint64) error {
+	var segments []byte
+	if packet != nil {
+		for n := len(packet); ; n -= 255 {
+			if n < 255 {
+				segments = append(segments, byte(n))
+				break
+			}
+			segments = append(segments, 255)
+		}
+	}
+
+	page := make([]byte, 27, 27+len(segments)+len(packet))
+	copy(page, "OggS")
+	page[5] = headerType
+	binary.LittleEndian.PutUint64(page[6:], granule)
+	binary.LittleEndian.PutUint32(page[14:], oggSerial)
+	binary.LittleEndian.PutUint32(page[18:], s.sequence)
+	page[26] = byte(len(segments))
+	page = append(page, segments...)
+	page = append(page, packet...)
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+
+	s.sequence++
+	_, err := s.file.Write(page)
+	return err
+}
+
+var oggCRCTable = func() (table [256]uint32) {
+	for i := range table {
+		r := uint32(i) << 24
+		for range 8 {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func oggCRC(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
@@ -0,0 +1,254 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// toneFrames returns n frames of a 440Hz stereo sine wave.
+func toneFrames(n int) [][]int16 {
+	frames := make([][]int16, n)
+	for f := range frames {
+		frame := make([]int16, FRAME_SIZE*CHANNELS)
+		for i := 0; i < FRAME_SIZE; i++ {
+			sample := int16(8000 * math.Sin(2*math.Pi*440*float64(f*FRAME_SIZE+i)/FRAME_RATE))
+			frame[i*CHANNELS] = sample
+			frame[i*CHANNELS+1] = sample
+		}
+		frames[f] = frame
+	}
+	return frames
+}
+
+func pcmBytes(frames [][]int16) []byte {
+	var buf bytes.Buffer
+	for _, frame := range frames {
+		binary.Write(&buf, binary.LittleEndian, frame)
+	}
+	return buf.Bytes()
+}
+
+// writeFixture writes frames as a plain 16-bit stereo WAV file.
+func writeFixture(t *testing.T, path string, frames [][]int16) {
+	t.Helper()
+	data := pcmBytes(frames)
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(data)))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []any{
+		uint32(16), uint16(1), uint16(CHANNELS), uint32(FRAME_RATE),
+		uint32(FRAME_RATE * CHANNELS * 2), uint16(CHANNELS * 2), uint16(16),
+	} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readWAV checks the header written by WAVSink and returns the samples.
+func readWAV(t *testing.T, path string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) < wavHeaderSize || string(b[0:4]) != "RIFF" || string(b[8:16]) != "WAVEfmt " || string(b[36:40]) != "data" {
+		t.Fatalf("bad WAV header: %q", b[:min(len(b), wavHeaderSize)])
+	}
+	le := binary.LittleEndian
+	if got := le.Uint16(b[20:]); got != 1 {
+		t.Errorf("format = %d, want PCM", got)
+	}
+	if got := le.Uint16(b[22:]); got != CHANNELS {
+		t.Errorf("channels = %d, want %d", got, CHANNELS)
+	}
+	if got := le.Uint32(b[24:]); got != FRAME_RATE {
+		t.Errorf("sample rate = %d, want %d", got, FRAME_RATE)
+	}
+	if got := le.Uint16(b[34:]); got != 16 {
+		t.Errorf("bits per sample = %d, want 16", got)
+	}
+	dataSize := le.Uint32(b[40:])
+	if int(dataSize) != len(b)-wavHeaderSize {
+		t.Errorf("data size = %d, but %d bytes follow the header", dataSize, len(b)-wavHeaderSize)
+	}
+	if got := le.Uint32(b[4:]); got != 36+dataSize {
+		t.Errorf("RIFF size = %d, want %d", got, 36+dataSize)
+	}
+	return b[wavHeaderSize:]
+}
+
+func TestWAVSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := toneFrames(10)
+	for _, frame := range frames {
+		if err := sink.(PCMWriter).WritePCM(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.(PCMWriter).WritePCM(frames[0]); err == nil {
+		t.Error("WritePCM after Close succeeded")
+	}
+
+	if got := readWAV(t, path); !bytes.Equal(got, pcmBytes(frames)) {
+		t.Errorf("got %d bytes of audio, want the %d written", len(got), len(pcmBytes(frames)))
+	}
+}
+
+type oggPage struct {
+	headerType byte
+	granule    uint64
+	sequence   uint32
+	packet     []byte
+}
+
+// readOgg splits an Ogg file into pages, checking each page's CRC.
+func readOgg(t *testing.T, path string) []oggPage {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pages []oggPage
+	for len(b) > 0 {
+		if len(b) < 27 || string(b[:4]) != "OggS" {
+			t.Fatalf("page %d: bad capture pattern", len(pages))
+		}
+		n := int(b[26])
+		size := 27 + n
+		for _, lacing := range b[27 : 27+n] {
+			size += int(lacing)
+		}
+		page := append([]byte(nil), b[:size]...)
+		crc := binary.LittleEndian.Uint32(page[22:])
+		binary.LittleEndian.PutUint32(page[22:], 0)
+		if oggCRC(page) != crc {
+			t.Fatalf("page %d: bad CRC", len(pages))
+		}
+		if serial := binary.LittleEndian.Uint32(page[14:]); serial != oggSerial {
+			t.Errorf("page %d: serial = %x, want %x", len(pages), serial, oggSerial)
+		}
+		pages = append(pages, oggPage{
+			headerType: page[5],
+			granule:    binary.LittleEndian.Uint64(page[6:]),
+			sequence:   binary.LittleEndian.Uint32(page[18:]),
+			packet:     page[27+n:],
+		})
+		b = b[size:]
+	}
+	return pages
+}
+
+func TestOggSink(t *testing.T) {
+	encoder, err := NewEncoder(EncoderSettingsForBitrate(DEFAULT_BITRATE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "out.ogg")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var packets [][]byte
+	for _, frame := range toneFrames(n) {
+		packet, err := encoder.Encode(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, packet)
+		if err := sink.WriteOpus(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pages := readOgg(t, path)
+	if len(pages) != n+2 {
+		t.Fatalf("got %d pages, want %d: OpusHead, OpusTags and one per packet", len(pages), n+2)
+	}
+	if !bytes.HasPrefix(pages[0].packet, []byte("OpusHead")) || pages[0].headerType != 0x02 {
+		t.Errorf("first page isn't a beginning-of-stream OpusHead")
+	}
+	if !bytes.HasPrefix(pages[1].packet, []byte("OpusTags")) {
+		t.Errorf("second page isn't OpusTags")
+	}
+	for i, page := range pages {
+		if page.sequence != uint32(i) {
+			t.Errorf("page %d has sequence number %d", i, page.sequence)
+		}
+	}
+	for i, packet := range packets {
+		page := pages[i+2]
+		if !bytes.Equal(page.packet, packet) {
+			t.Errorf("page %d doesn't hold packet %d", i+2, i)
+		}
+		if want := uint64((i + 1) * FRAME_SIZE); page.granule != want {
+			t.Errorf("page %d: granule = %d, want %d", i+2, page.granule, want)
+		}
+	}
+	if last := pages[len(pages)-1]; last.headerType != 0x04 {
+		t.Errorf("last page's header type = %#x, want end-of-stream", last.headerType)
+	}
+}
+
+// TestPlayFixture plays a WAV fixture through ffmpeg and a Connection into a
+// WAV sink, and expects the same audio out.
+func TestPlayFixture(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+
+	dir := t.TempDir()
+	fixture := filepath.Join(dir, "tone.wav")
+	frames := toneFrames(25) // half a second
+	writeFixture(t, fixture, frames)
+
+	out := filepath.Join(dir, "out.wav")
+	sink, err := NewWAVSink(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := NewConnection(sink, nil)
+	if err := conn.Play(fixture, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Play returns once ffmpeg is done; the last frames may still be on
+	// their way to the sink.
+	deadline := time.Now().Add(2 * time.Second)
+	for conn.frames.Load() < int64(len(frames)) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	conn.Stop()
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := conn.frames.Load(); got != int64(len(frames)) {
+		t.Errorf("sent %d frames, want %d", got, len(frames))
+	}
+	if got := readWAV(t, out); !bytes.Equal(got, pcmBytes(frames)) {
+		t.Errorf("got %d bytes of audio back, want the fixture's %d", len(got), len(pcmBytes(frames)))
+	}
+}
@@ -0,0 +1,111 @@
+package audio
+
+import (
+	"fmt"
+	"musicbot/store"
+	"sync"
+	"time"
+)
+
+// MAX_HISTORY is how many played tracks are kept per guild.
+const MAX_HISTORY = 200
+
+type HistoryEntry struct {
+	Track       *Track    `json:"track"`
+	RequesterID string    `json:"requester_id,omitempty"`
+	PlayedAt    time.Time `json:"played_at"`
+	Skipped     bool      `json:"skipped,omitempty"`
+}
+
+// History is a guild's bounded list of played tracks, oldest first.
+type History struct {
+	entries []HistoryEntry
+	guildID string
+	store   *store.Store
+	sync.Mutex
+}
+
+func (h *History) Add(entry HistoryEntry) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > MAX_HISTORY {
+		h.entries = h.entries[len(h.entries)-MAX_HISTORY:]
+	}
+	h.save()
+}
+
+// Pop removes and returns the most recent entry.
+func (h *History) Pop() (HistoryEntry, bool) {
+	h.Lock()
+	defer h.Unlock()
+
+	if len(h.entries) == 0 {
+		return HistoryEntry{}, false
+	}
+	entry := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	h.save()
+	return entry, true
+}
+
+// Recent returns up to n entries starting offset entries back from the
+// newest, newest first.
+func (h *History) Recent(offset, n int) []HistoryEntry {
+	h.Lock()
+	defer h.Unlock()
+
+	var out []HistoryEntry
+	for i := len(h.entries) - 1 - offset; i >= 0 && len(out) < n; i-- {
+		out = append(out, h.entries[i])
+	}
+	return out
+}
+
+func (h *History) Len() int {
+	h.Lock()
+	defer h.Unlock()
+	return len(h.entries)
+}
+
+// save persists the history. Callers must hold the lock.
+func (h *History) save() {
+	if h.store == nil {
+		return
+	}
+	if err := h.store.Save("history/"+h.guildID, h.entries); err != nil {
+		fmt.Println("Failed to save history for", h.guildID+":", err)
+	}
+}
+
+type HistoryManager struct {
+	histories map[string]*History // guildID → History
+	store     *store.Store
+	sync.Mutex
+}
+
+func NewHistoryManager(st *store.Store) *HistoryManager {
+	return &HistoryManager{
+		histories: make(map[string]*History),
+		store:     st,
+	}
+}
+
+// Get returns the guild's history, loading it from the store on first use.
+func (hm *HistoryManager) Get(guildID string) *History {
+	hm.Lock()
+	defer hm.Unlock()
+
+	if h, ok := hm.histories[guildID]; ok {
+		return h
+	}
+	h := &History{guildID: guildID, store: hm.store}
+	if hm.store != nil {
+		if _, err := hm.store.Load("history/"+guildID, &h.entries); err != nil {
+			fmt.Println("Failed to load history for", guildID+":", err)
+		}
+	}
+	hm.histories[guildID] = h
+	return h
+}
@@ -0,0 +1,156 @@
+package audio
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// MAX_JOURNAL is how many queue changes can be undone per guild.
+const MAX_JOURNAL = 20
+
+var (
+	ErrJournalEmpty = errors.New("nothing to undo or redo")
+	ErrNotAllowed   = errors.New("not allowed to undo or redo this change")
+)
+
+// JournalEntry is the queue as it was before a change, and who made it.
+type JournalEntry struct {
+	Action   string
+	UserID   string
+	At       time.Time
+	Tracks   []*Track
+	LoopMode LoopMode
+
+	// After is what the change left queued. Tracks it kept that have
+	// left the queue since were played, and don't come back on undo.
+	After []*Track
+}
+
+// restore returns the tracks to go back to given what is queued now:
+// those from before the change that it removed or that are still queued.
+func (e JournalEntry) restore(queued []*Track) []*Track {
+	now := make(map[*Track]bool)
+	for _, t := range queued {
+		now[t] = true
+	}
+	after := make(map[*Track]bool)
+	for _, t := range e.After {
+		after[t] = true
+	}
+
+	var tracks []*Track
+	for _, t := range e.Tracks {
+		if now[t] || !after[t] {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// Journal holds a guild's undo and redo stacks of queue changes.
+type Journal struct {
+	undo []JournalEntry
+	redo []JournalEntry
+	sync.Mutex
+}
+
+// Record saves the state before a new change. It clears the redo stack,
+// since those changes no longer follow from the queue.
+func (j *Journal) Record(entry JournalEntry) {
+	j.Lock()
+	defer j.Unlock()
+
+	j.undo = append(j.undo, entry)
+	if len(j.undo) > MAX_JOURNAL {
+		j.undo = j.undo[len(j.undo)-MAX_JOURNAL:]
+	}
+	j.redo = nil
+}
+
+// Replace swaps every occurrence of old for t, as Queue.Replace does for
+// the queue, so a track resolved while queued isn't taken for played.
+func (j *Journal) Replace(old, t *Track) {
+	j.Lock()
+	defer j.Unlock()
+
+	for _, entries := range [][]JournalEntry{j.undo, j.redo} {
+		for _, entry := range entries {
+			for _, tracks := range [][]*Track{entry.Tracks, entry.After} {
+				for i, other := range tracks {
+					if other == old {
+						tracks[i] = t
+					}
+				}
+			}
+		}
+	}
+}
+
+// Undo pops the last change if allow permits, saving now (the queue as it
+// is) so the change can be redone, and returns the state to go back to.
+// It returns the change allow refused along with ErrNotAllowed.
+func (j *Journal) Undo(now JournalEntry, allow func(entry JournalEntry) bool) (JournalEntry, error) {
+	j.Lock()
+	defer j.Unlock()
+	return revert(&j.undo, &j.redo, now, allow)
+}
+
+// Redo pops the last undone change if allow permits, saving now so it can
+// be undone again, and returns the state to go forward to.
+func (j *Journal) Redo(now JournalEntry, allow func(entry JournalEntry) bool) (JournalEntry, error) {
+	j.Lock()
+	defer j.Unlock()
+	return revert(&j.redo, &j.undo, now, allow)
+}
+
+// revert moves the top entry of from to to, as Undo and Redo do. Callers
+// must hold the lock.
+func revert(from, to *[]JournalEntry, now JournalEntry, allow func(entry JournalEntry) bool) (JournalEntry, error) {
+	if len(*from) == 0 {
+		return JournalEntry{}, ErrJournalEmpty
+	}
+	entry := (*from)[len(*from)-1]
+	if !allow(entry) {
+		return entry, ErrNotAllowed
+	}
+	*from = (*from)[:len(*from)-1]
+
+	entry.Tracks = entry.restore(now.Tracks)
+	now.Action, now.UserID = entry.Action, entry.UserID
+	now.After = entry.Tracks
+	*to = append(*to, now)
+	return entry, nil
+}
+
+type JournalManager struct {
+	journals map[string]*Journal // guildID → Journal
+	sync.Mutex
+}
+
+func NewJournalManager() *JournalManager {
+	return &JournalManager{journals: make(map[string]*Journal)}
+}
+
+func (jm *JournalManager) Get(guildID string) *Journal {
+	jm.Lock()
+	defer jm.Unlock()
+
+	if _, ok := jm.journals[guildID]; !ok {
+		jm.journals[guildID] = &Journal{}
+	}
+	return jm.journals[guildID]
+}
+
+// State returns the queued tracks and loop mode.
+func (q *Queue) State() ([]*Track, LoopMode) {
+	q.Lock()
+	defer q.Unlock()
+	return append([]*Track(nil), q.Tracks...), q.LoopMode
+}
+
+// SetState replaces the queued tracks and loop mode, leaving the current
+// track alone.
+func (q *Queue) SetState(tracks []*Track, mode LoopMode) {
+	q.Edit(func(e *QueueEdit) { e.SetState(tracks, mode) })
+}
@@ -0,0 +1,92 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func allowAll(JournalEntry) bool { return true }
+
+func TestUndoDropsPlayedTracks(t *testing.T) {
+	a, b, c, d := &Track{URL: "a"}, &Track{URL: "b"}, &Track{URL: "c"}, &Track{URL: "d"}
+	var j Journal
+	// d was removed, leaving b and c; b has played since.
+	j.Record(JournalEntry{Action: "remove", Tracks: []*Track{b, c, d}, After: []*Track{b, c}})
+
+	entry, err := j.Undo(JournalEntry{Tracks: []*Track{c, a}}, allowAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*Track{c, d}; !reflect.DeepEqual(entry.Tracks, want) {
+		t.Errorf("undo restores %v, want %v", entry.Tracks, want)
+	}
+
+	// c plays before the redo, which takes d out again.
+	entry, err = j.Redo(JournalEntry{Tracks: []*Track{d}}, allowAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*Track{a}; !reflect.DeepEqual(entry.Tracks, want) {
+		t.Errorf("redo restores %v, want %v", entry.Tracks, want)
+	}
+}
+
+func TestUndoAfterReplace(t *testing.T) {
+	a, b := &Track{URL: "a"}, &Track{URL: "b"}
+	var j Journal
+	j.Record(JournalEntry{Action: "enqueue", Tracks: []*Track{a}, After: []*Track{a, b}})
+
+	// a is resolved while queued, which isn't the same as it playing.
+	resolved := &Track{URL: "a", Title: "A"}
+	j.Replace(a, resolved)
+	entry, err := j.Undo(JournalEntry{Tracks: []*Track{resolved, b}}, allowAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*Track{resolved}; !reflect.DeepEqual(entry.Tracks, want) {
+		t.Errorf("undo restores %v, want %v", entry.Tracks, want)
+	}
+}
+
+func TestJournalRefusals(t *testing.T) {
+	var j Journal
+	if _, err := j.Undo(JournalEntry{}, allowAll); err != ErrJournalEmpty {
+		t.Errorf("undo with nothing journaled: %v", err)
+	}
+
+	j.Record(JournalEntry{Action: "clear", UserID: "alice"})
+	entry, err := j.Undo(JournalEntry{}, func(JournalEntry) bool { return false })
+	if err != ErrNotAllowed || entry.UserID != "alice" {
+		t.Errorf("refused undo returned %+v, %v", entry, err)
+	}
+	// A refusal leaves the change to be undone.
+	if _, err := j.Undo(JournalEntry{}, allowAll); err != nil {
+		t.Errorf("undo after a refusal: %v", err)
+	}
+	if _, err := j.Redo(JournalEntry{}, allowAll); err != nil {
+		t.Errorf("redo: %v", err)
+	}
+
+	// A new change clears what could be redone.
+	j.Undo(JournalEntry{}, allowAll)
+	j.Record(JournalEntry{Action: "shuffle"})
+	if _, err := j.Redo(JournalEntry{}, allowAll); err != ErrJournalEmpty {
+		t.Errorf("redo after a new change: %v", err)
+	}
+}
+
+func TestJournalKeepsTheLastChanges(t *testing.T) {
+	var j Journal
+	for i := 0; i < MAX_JOURNAL+5; i++ {
+		j.Record(JournalEntry{LoopMode: LoopMode(i)})
+	}
+	for i := MAX_JOURNAL + 4; i >= 5; i-- {
+		entry, err := j.Undo(JournalEntry{}, allowAll)
+		if err != nil || entry.LoopMode != LoopMode(i) {
+			t.Fatalf("undo %d gave %+v, %v", i, entry, err)
+		}
+	}
+	if _, err := j.Undo(JournalEntry{}, allowAll); err != ErrJournalEmpty {
+		t.Errorf("more than %d changes could be undone", MAX_JOURNAL)
+	}
+}
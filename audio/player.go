@@ -0,0 +1,568 @@
+package audio
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type PlayerState int
+
+const (
+	StateIdle PlayerState = iota
+	StateResolving
+	StatePlaying
+	StatePaused
+	StateStopping
+)
+
+func (s PlayerState) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateResolving:
+		return "resolving"
+	case StatePlaying:
+		return "playing"
+	case StatePaused:
+		return "paused"
+	case StateStopping:
+		return "stopping"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	ErrNotPlaying    = errors.New("nothing is playing")
+	ErrAlreadyPaused = errors.New("already paused")
+	ErrNotPaused     = errors.New("not paused")
+	ErrTrackChanged  = errors.New("the track has changed")
+)
+
+// EncoderSource returns the encoder to use for the next track.
+type EncoderSource func() (*Encoder, error)
+
+// Resolver fills in a track's metadata before it is played.
+type Resolver func(t Track) Track
+
+// Recommender picks a track to play after last when the queue runs dry,
+// or returns nil to let the player go idle. It runs off the player's
+// goroutine, so it may be slow.
+type Recommender func(guildID string, last *Track) *Track
+
+type commandKind int
+
+const (
+	cmdAttach commandKind = iota
+	cmdDetach
+	cmdStart
+	cmdSkip
+	cmdStop
+	cmdPause
+	cmdResume
+	cmdInterrupt
+	cmdSleep
+	cmdSleepAfterTrack
+	cmdCancelSleep
+)
+
+type playerCommand struct {
+	kind     commandKind
+	sink     AudioSink
+	encoder  EncoderSource
+	track    *Track
+	tracks   []*Track
+	requeue  bool
+	admit    func(e *QueueEdit, tracks []*Track) []*Track
+	sleep    time.Duration
+	playTime bool
+	reply    chan error
+}
+
+type resolvedTrack struct {
+	generation int
+	original   *Track
+	track      *Track
+	autoplay   bool // picked by the Recommender; track is nil if it found nothing
+}
+
+type finishedTrack struct {
+	generation int
+	err        error
+}
+
+// Player owns a guild's playback. All state changes happen on its own
+// goroutine in response to commands, so callers never touch the queue's
+// current track or the connection directly.
+type Player struct {
+	GuildID string
+	Queue   *Queue
+
+	commands   chan playerCommand
+	resolved   chan resolvedTrack
+	finished   chan finishedTrack
+	sleepFired chan int
+	bus        *EventBus
+	resolve    Resolver
+	recommend  Recommender
+
+	// Owned by the run goroutine.
+	state           PlayerState
+	sink            AudioSink
+	encoder         EncoderSource
+	conn            *Connection
+	track           *Track // the track conn is playing
+	last            *Track // the last track that played, for the Recommender
+	skipped         bool
+	interrupted     bool
+	generation      int
+	sleep           *sleepTimer
+	sleepGeneration int
+
+	mu          sync.RWMutex
+	observed    PlayerState
+	playing     *Connection
+	textChannel string
+	sleepView   *SleepStatus
+	sleepViewAt time.Time
+}
+
+func newPlayer(guildID string, queue *Queue, resolve Resolver, recommend Recommender, bus *EventBus) *Player {
+	p := &Player{
+		GuildID:    guildID,
+		Queue:      queue,
+		commands:   make(chan playerCommand),
+		resolved:   make(chan resolvedTrack),
+		finished:   make(chan finishedTrack),
+		sleepFired: make(chan int),
+		bus:        bus,
+		resolve:    resolve,
+		recommend:  recommend,
+	}
+	go p.run()
+	return p
+}
+
+// State returns the player's current state.
+func (p *Player) State() PlayerState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.observed
+}
+
+// Position returns how far into the current track playback is.
+func (p *Player) Position() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.playing == nil {
+		return 0
+	}
+	return p.playing.Position()
+}
+
+// TextChannel returns the channel playback announcements go to.
+func (p *Player) TextChannel() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.textChannel
+}
+
+func (p *Player) SetTextChannel(channelID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.textChannel = channelID
+}
+
+// Attach sets the sink and encoder used for the following tracks.
+func (p *Player) Attach(sink AudioSink, encoder EncoderSource) {
+	p.send(playerCommand{kind: cmdAttach, sink: sink, encoder: encoder})
+}
+
+// Detach stops playback and drops the sink, e.g. after leaving voice.
+func (p *Player) Detach() {
+	p.send(playerCommand{kind: cmdDetach})
+}
+
+// Start begins playing the queue if the player is idle.
+func (p *Player) Start() {
+	p.send(playerCommand{kind: cmdStart})
+}
+
+func (p *Player) Skip() error {
+	return p.send(playerCommand{kind: cmdSkip})
+}
+
+// SkipTrack skips t if it is still the current track, so that a skip
+// decided on for one track never lands on the next.
+func (p *Player) SkipTrack(t *Track) error {
+	return p.send(playerCommand{kind: cmdSkip, track: t})
+}
+
+// Stop clears the queue and ends the current track.
+func (p *Player) Stop() {
+	p.send(playerCommand{kind: cmdStop})
+}
+
+func (p *Player) Pause() error {
+	return p.send(playerCommand{kind: cmdPause})
+}
+
+func (p *Player) Resume() error {
+	return p.send(playerCommand{kind: cmdResume})
+}
+
+// Interrupt plays tracks straight away. When requeue is set the track
+// being played goes back to the head of the queue behind them, to resume
+// from where it was interrupted.
+func (p *Player) Interrupt(tracks []*Track, requeue bool) {
+	p.InterruptWith(tracks, requeue, nil)
+}
+
+// InterruptWith is Interrupt with admit choosing which of tracks to play.
+// It runs with the queue locked, so it can check them against what is
+// queued and be sure nothing changes before they are added. If it returns
+// none, nothing is interrupted.
+func (p *Player) InterruptWith(tracks []*Track, requeue bool, admit func(e *QueueEdit, tracks []*Track) []*Track) {
+	p.send(playerCommand{kind: cmdInterrupt, tracks: tracks, requeue: requeue, admit: admit})
+}
+
+func (p *Player) send(c playerCommand) error {
+	c.reply = make(chan error, 1)
+	p.commands <- c
+	return <-c.reply
+}
+
+func (p *Player) run() {
+	for {
+		select {
+		case c := <-p.commands:
+			c.reply <- p.handle(c)
+		case r := <-p.resolved:
+			p.onResolved(r)
+		case f := <-p.finished:
+			p.onFinished(f)
+		case generation := <-p.sleepFired:
+			p.onSleepFired(generation)
+		}
+	}
+}
+
+func (p *Player) handle(c playerCommand) error {
+	switch c.kind {
+	case cmdAttach:
+		p.sink = c.sink
+		p.encoder = c.encoder
+
+	case cmdDetach:
+		p.clearSleep()
+		p.last = nil
+		p.halt()
+		p.sink = nil
+		p.encoder = nil
+
+	case cmdStart:
+		if p.state == StateIdle {
+			p.next()
+		}
+
+	case cmdSkip:
+		if c.track != nil && c.track != p.Queue.Current() {
+			return ErrTrackChanged
+		}
+		switch p.state {
+		case StatePlaying, StatePaused:
+			p.skipped = true
+			p.conn.Stop()
+		case StateResolving:
+			p.skipped = true
+			p.generation++
+			p.next()
+		default:
+			return ErrNotPlaying
+		}
+
+	case cmdStop:
+		p.clearSleep()
+		p.Queue.Clear()
+		p.last = nil
+		p.halt()
+
+	case cmdInterrupt:
+		var position time.Duration
+		if p.state == StatePlaying || p.state == StatePaused {
+			position = p.conn.Position()
+		}
+		requeue := c.requeue && p.state != StateIdle && p.state != StateStopping
+		tracks := c.tracks
+		p.Queue.Edit(func(e *QueueEdit) {
+			if c.admit != nil {
+				tracks = c.admit(e, tracks)
+			}
+			if len(tracks) == 0 {
+				return
+			}
+			if requeue {
+				e.Requeue(position)
+			}
+			e.PushFront(tracks...)
+		})
+		if len(tracks) == 0 {
+			return nil
+		}
+
+		switch p.state {
+		case StatePlaying, StatePaused:
+			p.interrupted = true
+			p.conn.Stop()
+		case StateResolving:
+			p.generation++
+			p.interrupted = true
+			p.next()
+		case StateIdle:
+			p.next()
+		}
+
+	case cmdPause:
+		switch p.state {
+		case StatePlaying:
+			p.conn.SetPaused(true)
+			p.setState(StatePaused)
+			p.bus.Publish(Paused{GuildID: p.GuildID, Track: p.track})
+		case StatePaused:
+			return ErrAlreadyPaused
+		default:
+			return ErrNotPlaying
+		}
+
+	case cmdResume:
+		switch p.state {
+		case StatePaused:
+			p.conn.SetPaused(false)
+			p.setState(StatePlaying)
+			p.bus.Publish(Resumed{GuildID: p.GuildID, Track: p.track})
+		case StatePlaying:
+			return ErrNotPaused
+		default:
+			return ErrNotPlaying
+		}
+
+	case cmdSleep:
+		return p.startSleep(c.sleep, c.playTime, false)
+
+	case cmdSleepAfterTrack:
+		return p.startSleep(0, false, true)
+
+	case cmdCancelSleep:
+		if p.sleep == nil {
+			return ErrNoSleep
+		}
+		p.clearSleep()
+	}
+	return nil
+}
+
+// halt ends whatever is in progress without touching the queue.
+func (p *Player) halt() {
+	switch p.state {
+	case StatePlaying, StatePaused:
+		p.setState(StateStopping)
+		p.conn.Stop()
+	case StateResolving:
+		p.generation++
+		p.Queue.SetCurrent(nil)
+		p.setState(StateIdle)
+	}
+}
+
+// next dequeues the following track and starts resolving it. When the
+// queue is empty it asks the Recommender for one, or goes idle.
+func (p *Player) next() {
+	if p.skipped || p.interrupted {
+		// Skipping must not replay the track under LoopOne.
+		p.Queue.SetCurrent(nil)
+		p.skipped = false
+		p.interrupted = false
+	}
+
+	var track *Track
+	if p.sink != nil {
+		track = p.Queue.Dequeue()
+	}
+	if track == nil && p.sink != nil && p.recommend != nil && p.last != nil {
+		p.autoplay(p.last)
+		return
+	}
+	if track == nil {
+		p.Queue.SetCurrent(nil)
+		p.setState(StateIdle)
+		return
+	}
+
+	p.generation++
+	generation := p.generation
+	p.setState(StateResolving)
+
+	go func() {
+		resolved := *track
+		if p.resolve != nil {
+			resolved = p.resolve(resolved)
+		}
+		p.resolved <- resolvedTrack{generation: generation, original: track, track: &resolved}
+	}()
+}
+
+// autoplay asks the Recommender for a track to follow last and resolves it.
+func (p *Player) autoplay(last *Track) {
+	p.generation++
+	generation := p.generation
+	p.Queue.SetCurrent(nil)
+	p.setState(StateResolving)
+
+	go func() {
+		r := resolvedTrack{generation: generation, autoplay: true}
+		if track := p.recommend(p.GuildID, last); track != nil {
+			resolved := *track
+			if p.resolve != nil {
+				resolved = p.resolve(resolved)
+			}
+			r.original, r.track = track, &resolved
+		}
+		p.resolved <- r
+	}()
+}
+
+func (p *Player) onResolved(r resolvedTrack) {
+	if r.generation != p.generation || p.state != StateResolving {
+		return
+	}
+	if r.autoplay {
+		if r.track == nil {
+			p.setState(StateIdle)
+			return
+		}
+		p.Queue.SetCurrent(r.original)
+	}
+	offset := r.track.Offset
+	r.track.Offset = 0
+	p.Queue.Replace(r.original, r.track)
+
+	encoder, err := p.encoder()
+	if err != nil {
+		p.bus.Publish(TrackEnded{GuildID: p.GuildID, Track: r.track, Reason: EndError, Err: err})
+		p.next()
+		return
+	}
+
+	conn := NewConnection(p.sink, encoder)
+	p.track = r.track
+	p.setConn(conn)
+	p.setState(StatePlaying)
+	p.bus.Publish(TrackStarted{GuildID: p.GuildID, Track: r.track})
+
+	generation := p.generation
+	go func() {
+		err := conn.Play(r.track.URL, offset)
+		p.finished <- finishedTrack{generation: generation, err: err}
+	}()
+}
+
+func (p *Player) onFinished(f finishedTrack) {
+	if f.generation != p.generation {
+		return
+	}
+	track := p.track
+	played := p.conn.Played()
+	p.track = nil
+	p.setConn(nil)
+
+	ended := TrackEnded{GuildID: p.GuildID, Track: track, Reason: EndFinished, Played: played}
+	switch {
+	case f.err != nil:
+		ended.Reason = EndError
+		ended.Err = f.err
+	case p.state == StateStopping:
+		ended.Reason = EndStopped
+	case p.interrupted:
+		ended.Reason = EndInterrupted
+	case p.skipped:
+		ended.Reason = EndSkipped
+	}
+	p.bus.Publish(ended)
+
+	// A track that failed or was stopped is no basis for recommendations.
+	p.last = track
+	if ended.Reason == EndError || ended.Reason == EndStopped {
+		p.last = nil
+	}
+
+	if p.state == StateStopping {
+		p.Queue.SetCurrent(nil)
+		p.setState(StateIdle)
+		return
+	}
+	if p.sleep != nil && p.sleep.afterTrack && ended.Reason != EndInterrupted {
+		p.expireSleep()
+		return
+	}
+	p.next()
+}
+
+func (p *Player) setConn(conn *Connection) {
+	p.conn = conn
+	p.mu.Lock()
+	p.playing = conn
+	p.mu.Unlock()
+}
+
+func (p *Player) setState(state PlayerState) {
+	from := p.state
+	if from == state && state == StateIdle {
+		return
+	}
+	p.state = state
+
+	p.mu.Lock()
+	p.observed = state
+	p.mu.Unlock()
+
+	p.bus.Publish(StateChanged{GuildID: p.GuildID, From: from, To: state})
+
+	// A sleep timer belongs to the playback it was set for.
+	if state == StateIdle {
+		p.clearSleep()
+	} else {
+		p.runSleep()
+	}
+}
+
+type PlayerManager struct {
+	players   map[string]*Player // guildID → Player
+	queues    *QueueManager
+	resolve   Resolver
+	recommend Recommender
+	bus       *EventBus
+	sync.Mutex
+}
+
+// NewPlayerManager creates players on demand for queues from queues,
+// publishing their events on bus. recommend may be nil to disable autoplay.
+func NewPlayerManager(queues *QueueManager, resolve Resolver, recommend Recommender, bus *EventBus) *PlayerManager {
+	return &PlayerManager{
+		players:   make(map[string]*Player),
+		queues:    queues,
+		resolve:   resolve,
+		recommend: recommend,
+		bus:       bus,
+	}
+}
+
+func (pm *PlayerManager) Get(guildID string) *Player {
+	pm.Lock()
+	defer pm.Unlock()
+
+	if _, ok := pm.players[guildID]; !ok {
+		pm.players[guildID] = newPlayer(guildID, pm.queues.Get(guildID), pm.resolve, pm.recommend, pm.bus)
+	}
+	return pm.players[guildID]
+}
@@ -0,0 +1,274 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFFmpeg stands in for ffmpeg: it copies the file given with -i to
+// stdout, after a pause if the file is named "slow", or plays until killed
+// if it is named "forever".
+const fakeFFmpeg = `#!/bin/sh
+while [ "$1" != "-i" ]; do shift; done
+case "$2" in
+*forever) exec sleep 60 ;;
+*slow) sleep 0.3 ;;
+esac
+exec cat "$2"
+`
+
+// playerTest is a player on a NullSink with ffmpeg stubbed out. Tracks
+// are local files: short ones that end straight away, and "forever" ones
+// that play until stopped.
+type playerTest struct {
+	t      *testing.T
+	dir    string
+	player *Player
+	queue  *Queue
+	events chan Event
+}
+
+func newPlayerTest(t *testing.T, resolve Resolver) *playerTest {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the ffmpeg stand-in is a shell script")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(fakeFFmpeg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	bus := NewEventBus()
+	events := make(chan Event, 1000)
+	t.Cleanup(bus.SubscribeAll(func(ev Event) { events <- ev }))
+
+	queue := NewQueueManager(bus).Get("guild")
+	player := newPlayer("guild", queue, resolve, nil, bus)
+	player.Attach(NewNullSink(), func() (*Encoder, error) {
+		return NewEncoder(EncoderSettingsForBitrate(0))
+	})
+	t.Cleanup(player.Stop)
+
+	return &playerTest{t: t, dir: dir, player: player, queue: queue, events: events}
+}
+
+// short returns a track of a few frames of silence.
+func (pt *playerTest) short(name string) *Track {
+	path := filepath.Join(pt.dir, name)
+	if err := os.WriteFile(path, make([]byte, 5*FRAME_SIZE*CHANNELS*2), 0o644); err != nil {
+		pt.t.Fatal(err)
+	}
+	return &Track{URL: path, Title: name, Duration: "0:01"}
+}
+
+// slow returns a track that plays for a moment.
+func (pt *playerTest) slow(name string) *Track {
+	t := pt.short(name + "-slow")
+	t.Title = name
+	return t
+}
+
+// forever returns a track that plays until it is stopped.
+func (pt *playerTest) forever(name string) *Track {
+	t := pt.short(name + "-forever")
+	t.Title = name
+	return t
+}
+
+// next waits for the next event of type E, skipping other events.
+func next[E Event](pt *playerTest) E {
+	pt.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-pt.events:
+			if e, ok := ev.(E); ok {
+				return e
+			}
+		case <-timeout:
+			var zero E
+			pt.t.Fatalf("timed out waiting for %T", zero)
+			return zero
+		}
+	}
+}
+
+func (pt *playerTest) expectStarted(title string) {
+	pt.t.Helper()
+	if e := next[TrackStarted](pt); e.Track.Title != title {
+		pt.t.Fatalf("started %s, want %s", e.Track.Title, title)
+	}
+}
+
+func (pt *playerTest) expectEnded(title string, reason EndReason) {
+	pt.t.Helper()
+	e := next[TrackEnded](pt)
+	if e.Track.Title != title || e.Reason != reason {
+		pt.t.Fatalf("%s ended (%s), want %s to end (%s)", e.Track.Title, e.Reason, title, reason)
+	}
+}
+
+// expectStates checks the following state changes.
+func (pt *playerTest) expectStates(states ...PlayerState) {
+	pt.t.Helper()
+	for _, want := range states {
+		if e := next[StateChanged](pt); e.To != want {
+			pt.t.Fatalf("went from %s to %s, want %s", e.From, e.To, want)
+		}
+	}
+}
+
+func TestPlayerPlaysQueueInOrder(t *testing.T) {
+	pt := newPlayerTest(t, nil)
+	pt.queue.EnqueueMultiple([]*Track{pt.short("a"), pt.short("b")})
+	pt.player.Start()
+
+	pt.expectStarted("a")
+	pt.expectEnded("a", EndFinished)
+	pt.expectStarted("b")
+	pt.expectEnded("b", EndFinished)
+	pt.expectStates(StateIdle)
+	if pt.queue.Current() != nil || len(pt.queue.List()) != 0 {
+		t.Errorf("queue still holds %v and %v", pt.queue.Current(), pt.queue.List())
+	}
+}
+
+func TestPlayerStates(t *testing.T) {
+	pt := newPlayerTest(t, nil)
+	if err := pt.player.Skip(); err != ErrNotPlaying {
+		t.Errorf("Skip while idle = %v, want ErrNotPlaying", err)
+	}
+	if err := pt.player.Pause(); err != ErrNotPlaying {
+		t.Errorf("Pause while idle = %v, want ErrNotPlaying", err)
+	}
+
+	pt.queue.EnqueueMultiple([]*Track{pt.forever("a"), pt.forever("b")})
+	pt.player.Start()
+	pt.expectStates(StateResolving, StatePlaying)
+	pt.expectStarted("a")
+
+	if err := pt.player.Resume(); err != ErrNotPaused {
+		t.Errorf("Resume while playing = %v, want ErrNotPaused", err)
+	}
+	if err := pt.player.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	pt.expectStates(StatePaused)
+	if err := pt.player.Pause(); err != ErrAlreadyPaused {
+		t.Errorf("Pause while paused = %v, want ErrAlreadyPaused", err)
+	}
+	if err := pt.player.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	pt.expectStates(StatePlaying)
+
+	if err := pt.player.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	pt.expectEnded("a", EndSkipped)
+	pt.expectStates(StateResolving, StatePlaying)
+	pt.expectStarted("b")
+	if got := pt.player.State(); got != StatePlaying {
+		t.Errorf("State() = %s, want playing", got)
+	}
+
+	pt.player.Stop()
+	pt.expectStates(StateStopping)
+	pt.expectEnded("b", EndStopped)
+	pt.expectStates(StateIdle)
+	if pt.queue.Current() != nil {
+		t.Errorf("current track is %v after Stop", pt.queue.Current())
+	}
+}
+
+func TestPlayerSkipWhileResolving(t *testing.T) {
+	release := make(chan struct{})
+	pt := newPlayerTest(t, func(track Track) Track {
+		if track.Title == "slow" {
+			<-release
+		}
+		return track
+	})
+	defer close(release)
+
+	pt.queue.EnqueueMultiple([]*Track{pt.forever("slow"), pt.forever("b")})
+	pt.player.Start()
+	pt.expectStates(StateResolving)
+	if err := pt.player.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	// slow never started, so b is the first to.
+	pt.expectStarted("b")
+}
+
+func TestPlayerInterrupt(t *testing.T) {
+	pt := newPlayerTest(t, nil)
+	pt.queue.EnqueueMultiple([]*Track{pt.forever("a"), pt.forever("b")})
+	pt.player.Start()
+	pt.expectStarted("a")
+
+	pt.player.Interrupt([]*Track{pt.forever("now")}, true)
+	pt.expectEnded("a", EndInterrupted)
+	pt.expectStarted("now")
+	queued := pt.queue.List()
+	if len(queued) != 2 || queued[0].Title != "a" || queued[1].Title != "b" {
+		t.Errorf("queue = %v, want a requeued ahead of b", queued)
+	}
+}
+
+// TestPlayerConcurrentUse hammers a player from many goroutines at once.
+// Run with -race.
+func TestPlayerConcurrentUse(t *testing.T) {
+	pt := newPlayerTest(t, nil)
+	var tracks []*Track
+	for i := range 10 {
+		tracks = append(tracks, pt.forever(fmt.Sprint(i)))
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				switch (i + j) % 6 {
+				case 0:
+					pt.queue.Enqueue(tracks[j%len(tracks)])
+					pt.player.Start()
+				case 1:
+					pt.player.Skip()
+				case 2:
+					pt.player.Pause()
+				case 3:
+					pt.player.Resume()
+				case 4:
+					pt.player.Position()
+					pt.player.State()
+					pt.queue.Current()
+				case 5:
+					pt.queue.List()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	pt.player.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for pt.player.State() != StateIdle {
+		if time.Now().After(deadline) {
+			t.Fatalf("player is %s after Stop, want idle", pt.player.State())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pt.queue.Current() != nil || len(pt.queue.List()) != 0 {
+		t.Errorf("queue still holds %v and %v after Stop", pt.queue.Current(), pt.queue.List())
+	}
+}
@@ -1,15 +1,21 @@
 package audio
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 type QueueManager struct {
-	queues map[string]*Queue // guildID → Queue
+	queues    map[string]*Queue // guildID → Queue
+	bus       *EventBus
+	configure func(q *Queue)
 	sync.RWMutex
 }
 
-func NewQueueManager() *QueueManager {
+func NewQueueManager(bus *EventBus) *QueueManager {
 	return &QueueManager{
 		queues: make(map[string]*Queue),
+		bus:    bus,
 	}
 }
 
@@ -18,7 +24,89 @@ func (qm *QueueManager) Get(guildID string) *Queue {
 	defer qm.Unlock()
 
 	if _, ok := qm.queues[guildID]; !ok {
-		qm.queues[guildID] = &Queue{}
+		q := &Queue{guildID: guildID, bus: qm.bus}
+		if qm.configure != nil {
+			qm.configure(q)
+		}
+		qm.queues[guildID] = q
 	}
 	return qm.queues[guildID]
 }
+
+// OnCreate sets a function that applies guild settings to each queue as
+// it is created.
+func (qm *QueueManager) OnCreate(fn func(q *Queue)) {
+	qm.Lock()
+	defer qm.Unlock()
+	qm.configure = fn
+}
+
+// Guilds returns the IDs of every guild with a queue.
+func (qm *QueueManager) Guilds() []string {
+	qm.RLock()
+	defer qm.RUnlock()
+
+	guildIDs := make([]string, 0, len(qm.queues))
+	for guildID := range qm.queues {
+		guildIDs = append(guildIDs, guildID)
+	}
+	return guildIDs
+}
+
+// QueueSnapshot is a queue's persisted form.
+type QueueSnapshot struct {
+	Tracks   []*Track `json:"tracks"`
+	Current  *Track   `json:"current,omitempty"` // Offset holds the playback position
+	LoopMode LoopMode `json:"loop_mode"`
+	Shuffle  bool     `json:"shuffle,omitempty"`
+
+	VoiceChannelID string    `json:"voice_channel_id,omitempty"`
+	TextChannelID  string    `json:"text_channel_id,omitempty"`
+	SavedAt        time.Time `json:"saved_at"`
+}
+
+// Empty reports whether there is nothing to restore.
+func (s *QueueSnapshot) Empty() bool {
+	return s.Current == nil && len(s.Tracks) == 0
+}
+
+// Snapshot captures the queue with the current track at position.
+func (q *Queue) Snapshot(position time.Duration) *QueueSnapshot {
+	q.Lock()
+	defer q.Unlock()
+
+	snap := &QueueSnapshot{
+		Tracks:   append([]*Track(nil), q.Tracks...),
+		LoopMode: q.LoopMode,
+		Shuffle:  q.shuffle,
+		SavedAt:  time.Now(),
+	}
+	if q.current != nil {
+		current := *q.current
+		current.Offset = position
+		snap.Current = &current
+
+		// LoopAll has already re-queued the current track at the end.
+		if n := len(snap.Tracks); q.LoopMode == LoopAll && n > 0 && snap.Tracks[n-1] == q.current {
+			snap.Tracks = snap.Tracks[:n-1]
+		}
+	}
+	return snap
+}
+
+// Restore puts a snapshot's tracks ahead of anything queued since, with
+// the interrupted track first.
+func (q *Queue) Restore(snap *QueueSnapshot) {
+	defer q.changed()
+	q.Lock()
+	defer q.Unlock()
+
+	var tracks []*Track
+	if snap.Current != nil {
+		tracks = append(tracks, snap.Current)
+	}
+	tracks = append(tracks, snap.Tracks...)
+	q.Tracks = append(tracks, q.Tracks...)
+	q.LoopMode = snap.LoopMode
+	q.shuffle = snap.Shuffle
+}
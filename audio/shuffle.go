@@ -0,0 +1,92 @@
+package audio
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// SetRand makes the queue draw its randomness from r, so shuffles can be
+// reproduced from a seed.
+func (q *Queue) SetRand(r *rand.Rand) {
+	q.Lock()
+	defer q.Unlock()
+	q.rng = r
+}
+
+// random returns the queue's random source. Callers must hold the lock.
+func (q *Queue) random() *rand.Rand {
+	if q.rng == nil {
+		q.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return q.rng
+}
+
+// SmartShuffle shuffles the queue so tracks by the same uploader are spread
+// evenly across it, and within each uploader's tracks, those from the same
+// requester are spread evenly too.
+func (q *Queue) SmartShuffle() {
+	q.Edit(func(e *QueueEdit) { e.SmartShuffle() })
+}
+
+// SetShuffle turns shuffle mode on or off. In shuffle mode new tracks are
+// inserted at random positions instead of the end.
+func (q *Queue) SetShuffle(shuffle bool) {
+	defer q.changed()
+	q.Lock()
+	defer q.Unlock()
+	q.shuffle = shuffle
+}
+
+func (q *Queue) ShuffleMode() bool {
+	q.Lock()
+	defer q.Unlock()
+	return q.shuffle
+}
+
+// spread orders tracks so that each group of tracks sharing keys[0] is
+// evenly spaced: a group of n tracks lands at positions i/n plus one random
+// offset, and every track is then sorted by position. Each group is itself
+// ordered by the remaining keys first, and the last level is a plain
+// shuffle. This keeps tracks from one group about len(tracks)/n apart
+// instead of leaving them to chance.
+func spread(tracks []*Track, rng *rand.Rand, keys ...func(t *Track) string) []*Track {
+	out := append([]*Track(nil), tracks...)
+	if len(keys) == 0 {
+		rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+		return out
+	}
+
+	// Group in order of first appearance so a seed always gives the same
+	// result, then shuffle the group order to break ties fairly.
+	var order []string
+	groups := make(map[string][]*Track)
+	for _, t := range tracks {
+		k := keys[0](t)
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], t)
+	}
+	rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+
+	type placed struct {
+		pos   float64
+		track *Track
+	}
+	var all []placed
+	for _, k := range order {
+		group := spread(groups[k], rng, keys[1:]...)
+		n := float64(len(group))
+		offset := rng.Float64()
+		for i, t := range group {
+			all = append(all, placed{pos: (float64(i) + offset) / n, track: t})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].pos < all[j].pos })
+
+	for i, p := range all {
+		out[i] = p.track
+	}
+	return out
+}
@@ -0,0 +1,143 @@
+package audio
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// trackGroup is count tracks by one uploader, queued by one requester.
+type trackGroup struct {
+	uploader, requester string
+	count               int
+}
+
+func makeTracks(groups []trackGroup) []*Track {
+	var tracks []*Track
+	for _, g := range groups {
+		for i := 0; i < g.count; i++ {
+			tracks = append(tracks, &Track{
+				URL:         fmt.Sprintf("%s-%s-%d", g.uploader, g.requester, i),
+				Uploader:    g.uploader,
+				RequesterID: g.requester,
+			})
+		}
+	}
+	return tracks
+}
+
+func smartShuffled(tracks []*Track, seed int64) []*Track {
+	q := NewQueueManager(nil).Get("guild")
+	q.SetRand(rand.New(rand.NewSource(seed)))
+	q.EnqueueMultiple(tracks)
+	q.SmartShuffle()
+	return q.List()
+}
+
+// minGaps returns, for each key, the smallest distance between two of
+// its tracks in order.
+func minGaps(order []*Track, key func(t *Track) string) map[string]int {
+	last := make(map[string]int)
+	gaps := make(map[string]int)
+	for i, t := range order {
+		k := key(t)
+		if j, ok := last[k]; ok {
+			if gap, ok := gaps[k]; !ok || i-j < gap {
+				gaps[k] = i - j
+			}
+		}
+		last[k] = i
+	}
+	return gaps
+}
+
+// wantGaps is how far apart spread keeps the tracks of each key: n
+// tracks sit 1/n of the way apart, so every other key with m tracks has
+// at least m/n of its own in between.
+func wantGaps(tracks []*Track, key func(t *Track) string) map[string]int {
+	counts := make(map[string]int)
+	for _, t := range tracks {
+		counts[key(t)]++
+	}
+	want := make(map[string]int)
+	for k, n := range counts {
+		want[k] = 1
+		for other, m := range counts {
+			if other != k {
+				want[k] += m / n
+			}
+		}
+	}
+	return want
+}
+
+func checkSpread(t *testing.T, seed int64, order, tracks []*Track, what string, key func(t *Track) string) {
+	t.Helper()
+	want := wantGaps(tracks, key)
+	for k, gap := range minGaps(order, key) {
+		if gap < want[k] {
+			t.Errorf("seed %d: two tracks with %s %s are %d apart, want at least %d", seed, what, k, gap, want[k])
+		}
+	}
+}
+
+func TestSmartShuffleSpreadsUploaders(t *testing.T) {
+	tracks := makeTracks([]trackGroup{
+		{"a", "alice", 6}, {"b", "alice", 2}, {"b", "bob", 1}, {"c", "bob", 3},
+	})
+	uploader := func(t *Track) string { return t.Uploader }
+	for seed := int64(0); seed < 50; seed++ {
+		order := smartShuffled(tracks, seed)
+		if len(order) != len(tracks) {
+			t.Fatalf("seed %d: shuffled %d tracks into %d", seed, len(tracks), len(order))
+		}
+		checkSpread(t, seed, order, tracks, "uploader", uploader)
+	}
+}
+
+func TestSmartShuffleSpreadsRequesters(t *testing.T) {
+	// One uploader, so the requester level decides the whole order.
+	tracks := makeTracks([]trackGroup{
+		{"a", "alice", 4}, {"a", "bob", 4}, {"a", "carol", 2},
+	})
+	requester := func(t *Track) string { return t.RequesterID }
+	for seed := int64(0); seed < 50; seed++ {
+		checkSpread(t, seed, smartShuffled(tracks, seed), tracks, "requester", requester)
+	}
+
+	// With two uploaders, each one's tracks keep their requesters apart.
+	tracks = append(tracks, makeTracks([]trackGroup{
+		{"b", "alice", 3}, {"b", "dave", 3},
+	})...)
+	for seed := int64(0); seed < 50; seed++ {
+		order := smartShuffled(tracks, seed)
+		for _, uploader := range []string{"a", "b"} {
+			var mine, shuffled []*Track
+			for _, t := range tracks {
+				if t.Uploader == uploader {
+					mine = append(mine, t)
+				}
+			}
+			for _, t := range order {
+				if t.Uploader == uploader {
+					shuffled = append(shuffled, t)
+				}
+			}
+			checkSpread(t, seed, shuffled, mine, "requester", requester)
+		}
+	}
+}
+
+func TestSmartShuffleIsSeeded(t *testing.T) {
+	tracks := makeTracks([]trackGroup{
+		{"a", "alice", 5}, {"b", "bob", 5}, {"c", "carol", 5},
+	})
+	first, again := smartShuffled(tracks, 7), smartShuffled(tracks, 7)
+	if !reflect.DeepEqual(first, again) {
+		t.Error("the same seed gave different orders")
+	}
+	if other := smartShuffled(tracks, 8); reflect.DeepEqual(first, other) {
+		t.Error("different seeds gave the same order")
+	}
+}
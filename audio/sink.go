@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// AudioSink is where a Connection sends its audio. Frames are Opus encoded
+// by the guild's encoder unless the sink also implements PCMWriter.
+type AudioSink interface {
+	Speaking(speaking bool) error
+	WriteOpus(packet []byte) error
+	Close() error
+}
+
+// PCMWriter is implemented by sinks that take raw 48kHz stereo PCM frames.
+type PCMWriter interface {
+	WritePCM(frame []int16) error
+}
+
+var ErrSinkNotReady = errors.New("audio sink not ready")
+
+// DiscordSink sends Opus packets to a Discord voice connection.
+type DiscordSink struct {
+	voice *discordgo.VoiceConnection
+}
+
+func NewDiscordSink(voice *discordgo.VoiceConnection) *DiscordSink {
+	return &DiscordSink{voice: voice}
+}
+
+func (s *DiscordSink) Speaking(speaking bool) error {
+	return s.voice.Speaking(speaking)
+}
+
+func (s *DiscordSink) WriteOpus(packet []byte) error {
+	if !s.voice.Ready || s.voice.OpusSend == nil {
+		return ErrSinkNotReady
+	}
+	s.voice.OpusSend <- packet
+	return nil
+}
+
+func (s *DiscordSink) Close() error {
+	return s.voice.Disconnect()
+}
+
+// NullSink discards everything it receives, counting frames as it goes.
+type NullSink struct {
+	frames atomic.Int64
+	bytes  atomic.Int64
+}
+
+func NewNullSink() *NullSink {
+	return &NullSink{}
+}
+
+func (s *NullSink) Speaking(bool) error { return nil }
+
+func (s *NullSink) WriteOpus(packet []byte) error {
+	s.frames.Add(1)
+	s.bytes.Add(int64(len(packet)))
+	return nil
+}
+
+func (s *NullSink) Close() error { return nil }
+
+// Frames returns the number of frames written so far.
+func (s *NullSink) Frames() int64 { return s.frames.Load() }
+
+// Bytes returns the number of encoded bytes written so far.
+func (s *NullSink) Bytes() int64 { return s.bytes.Load() }
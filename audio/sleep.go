@@ -0,0 +1,186 @@
+package audio
+
+import (
+	"errors"
+	"time"
+)
+
+// SLEEP_WARNING is how long before a sleep timer runs out it warns.
+const SLEEP_WARNING = time.Minute
+
+var ErrNoSleep = errors.New("no sleep timer is set")
+
+// SleepWarning is published SLEEP_WARNING before a sleep timer runs out.
+type SleepWarning struct {
+	GuildID string
+	Left    time.Duration
+}
+
+// SleepExpired is published when a sleep timer stops playback.
+type SleepExpired struct {
+	GuildID string
+}
+
+func (e SleepWarning) Guild() string { return e.GuildID }
+func (e SleepExpired) Guild() string { return e.GuildID }
+
+// SleepStatus describes a player's sleep timer.
+type SleepStatus struct {
+	AfterTrack bool          // stops when the current track ends
+	PlayTime   bool          // counts down only while a track plays
+	Left       time.Duration // unset for AfterTrack
+	Running    bool          // false while a PlayTime timer waits for playback
+}
+
+// sleepTimer is owned by the run goroutine, like the rest of the player's
+// playback state.
+type sleepTimer struct {
+	afterTrack bool
+	playTime   bool
+	left       time.Duration // time left as of since
+	since      time.Time     // when the countdown last resumed; zero while held
+	warned     bool
+	warning    bool // the pending timer is the warning, not the end
+	timer      *time.Timer
+}
+
+func (s *sleepTimer) status() *SleepStatus {
+	status := &SleepStatus{AfterTrack: s.afterTrack, PlayTime: s.playTime, Left: s.left}
+	if !s.since.IsZero() {
+		status.Running = true
+		status.Left -= time.Since(s.since)
+	}
+	return status
+}
+
+// Sleep stops playback after d. With playTime, only time spent playing
+// counts, so pauses and gaps between tracks extend the timer.
+func (p *Player) Sleep(d time.Duration, playTime bool) error {
+	return p.send(playerCommand{kind: cmdSleep, sleep: d, playTime: playTime})
+}
+
+// SleepAfterTrack stops playback when the current track ends.
+func (p *Player) SleepAfterTrack() error {
+	return p.send(playerCommand{kind: cmdSleepAfterTrack})
+}
+
+func (p *Player) CancelSleep() error {
+	return p.send(playerCommand{kind: cmdCancelSleep})
+}
+
+// SleepStatus returns the sleep timer's state, or nil when none is set.
+func (p *Player) SleepStatus() *SleepStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.sleepView == nil {
+		return nil
+	}
+	status := *p.sleepView
+	if status.Running {
+		status.Left -= time.Since(p.sleepViewAt)
+	}
+	return &status
+}
+
+// startSleep replaces any sleep timer with one for d, or for the end of
+// the current track when afterTrack is set.
+func (p *Player) startSleep(d time.Duration, playTime, afterTrack bool) error {
+	if p.state == StateIdle || p.state == StateStopping {
+		return ErrNotPlaying
+	}
+	p.clearSleep()
+	p.sleep = &sleepTimer{left: d, playTime: playTime, afterTrack: afterTrack}
+	p.runSleep()
+	return nil
+}
+
+// runSleep starts or holds the countdown to match the player's state.
+func (p *Player) runSleep() {
+	s := p.sleep
+	if s == nil || s.afterTrack {
+		p.showSleep()
+		return
+	}
+
+	run := !s.playTime || p.state == StatePlaying
+	switch {
+	case run && s.since.IsZero():
+		s.since = time.Now()
+		p.scheduleSleep()
+	case !run && !s.since.IsZero():
+		s.left -= time.Since(s.since)
+		s.since = time.Time{}
+		s.timer.Stop()
+		p.sleepGeneration++
+	}
+	p.showSleep()
+}
+
+func (p *Player) scheduleSleep() {
+	s := p.sleep
+	wait := s.left
+	s.warning = !s.warned && s.left > SLEEP_WARNING
+	if s.warning {
+		wait -= SLEEP_WARNING
+	}
+
+	p.sleepGeneration++
+	generation := p.sleepGeneration
+	s.timer = time.AfterFunc(wait, func() { p.sleepFired <- generation })
+}
+
+func (p *Player) onSleepFired(generation int) {
+	s := p.sleep
+	if s == nil || generation != p.sleepGeneration {
+		return
+	}
+	s.left -= time.Since(s.since)
+	s.since = time.Now()
+
+	if s.warning {
+		s.warned = true
+		p.bus.Publish(SleepWarning{GuildID: p.GuildID, Left: s.left})
+		p.scheduleSleep()
+		p.showSleep()
+		return
+	}
+	p.expireSleep()
+}
+
+// expireSleep stops playback for a sleep timer that ran out.
+func (p *Player) expireSleep() {
+	p.clearSleep()
+	p.bus.Publish(SleepExpired{GuildID: p.GuildID})
+	p.Queue.Clear()
+	p.last = nil
+	if p.conn == nil && p.state != StateResolving {
+		p.Queue.SetCurrent(nil)
+		p.setState(StateIdle)
+		return
+	}
+	p.halt()
+}
+
+func (p *Player) clearSleep() {
+	if p.sleep == nil {
+		return
+	}
+	if p.sleep.timer != nil {
+		p.sleep.timer.Stop()
+	}
+	p.sleep = nil
+	p.sleepGeneration++
+	p.showSleep()
+}
+
+// showSleep publishes the sleep timer for SleepStatus.
+func (p *Player) showSleep() {
+	var view *SleepStatus
+	if p.sleep != nil {
+		view = p.sleep.status()
+	}
+	p.mu.Lock()
+	p.sleepView = view
+	p.sleepViewAt = time.Now()
+	p.mu.Unlock()
+}
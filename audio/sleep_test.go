@@ -0,0 +1,112 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSleep(t *testing.T) {
+	pt := newPlayerTest(t, nil)
+	if err := pt.player.Sleep(time.Minute, false); err != ErrNotPlaying {
+		t.Errorf("Sleep while idle = %v, want ErrNotPlaying", err)
+	}
+
+	pt.queue.EnqueueMultiple([]*Track{pt.forever("a"), pt.forever("b")})
+	pt.player.Start()
+	pt.expectStarted("a")
+	if err := pt.player.Sleep(100*time.Millisecond, false); err != nil {
+		t.Fatal(err)
+	}
+	if status := pt.player.SleepStatus(); status == nil || !status.Running || status.Left > 100*time.Millisecond {
+		t.Errorf("SleepStatus() = %+v, want a running timer", status)
+	}
+
+	next[SleepExpired](pt)
+	pt.expectEnded("a", EndStopped)
+	pt.expectStates(StateIdle)
+	if pt.queue.Current() != nil || len(pt.queue.List()) != 0 {
+		t.Errorf("queue still holds %v and %v", pt.queue.Current(), pt.queue.List())
+	}
+	if status := pt.player.SleepStatus(); status != nil {
+		t.Errorf("SleepStatus() = %+v after it ran out", status)
+	}
+}
+
+func TestSleepCancel(t *testing.T) {
+	pt := newPlayerTest(t, nil)
+	pt.queue.Enqueue(pt.forever("a"))
+	pt.player.Start()
+	pt.expectStarted("a")
+
+	if err := pt.player.CancelSleep(); err != ErrNoSleep {
+		t.Errorf("CancelSleep with no timer = %v, want ErrNoSleep", err)
+	}
+	pt.player.Sleep(50*time.Millisecond, false)
+	if err := pt.player.CancelSleep(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if state := pt.player.State(); state != StatePlaying {
+		t.Errorf("player is %s after the timer was cancelled", state)
+	}
+	if status := pt.player.SleepStatus(); status != nil {
+		t.Errorf("SleepStatus() = %+v after CancelSleep", status)
+	}
+}
+
+func TestSleepPlayTimeHoldsWhilePaused(t *testing.T) {
+	pt := newPlayerTest(t, nil)
+	pt.queue.Enqueue(pt.forever("a"))
+	pt.player.Start()
+	pt.expectStarted("a")
+
+	pt.player.Sleep(200*time.Millisecond, true)
+	pt.player.Pause()
+	time.Sleep(300 * time.Millisecond)
+	status := pt.player.SleepStatus()
+	if status == nil || status.Running || status.Left < 100*time.Millisecond {
+		t.Fatalf("SleepStatus() = %+v while paused, want a held timer", status)
+	}
+	if state := pt.player.State(); state != StatePaused {
+		t.Fatalf("player is %s, want it still paused", state)
+	}
+
+	pt.player.Resume()
+	next[SleepExpired](pt)
+	pt.expectEnded("a", EndStopped)
+}
+
+func TestSleepAfterTrack(t *testing.T) {
+	pt := newPlayerTest(t, nil)
+	pt.queue.EnqueueMultiple([]*Track{pt.slow("a"), pt.forever("b")})
+	pt.player.Start()
+	pt.expectStarted("a")
+	if err := pt.player.SleepAfterTrack(); err != nil {
+		t.Fatal(err)
+	}
+	if status := pt.player.SleepStatus(); status == nil || !status.AfterTrack {
+		t.Errorf("SleepStatus() = %+v, want after the track", status)
+	}
+
+	pt.expectEnded("a", EndFinished)
+	next[SleepExpired](pt)
+	pt.expectStates(StateIdle)
+	if len(pt.queue.List()) != 0 {
+		t.Errorf("b is still queued: %v", pt.queue.List())
+	}
+}
+
+func TestSleepAfterTrackSurvivesInterrupt(t *testing.T) {
+	pt := newPlayerTest(t, nil)
+	pt.queue.Enqueue(pt.forever("a"))
+	pt.player.Start()
+	pt.expectStarted("a")
+	pt.player.SleepAfterTrack()
+
+	// The track played instead is the one the timer waits for now.
+	pt.player.Interrupt([]*Track{pt.slow("now")}, false)
+	pt.expectEnded("a", EndInterrupted)
+	pt.expectStarted("now")
+	pt.expectEnded("now", EndFinished)
+	next[SleepExpired](pt)
+}
@@ -9,11 +9,10 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/bwmarrin/discordgo"
-	"layeh.com/gopus"
 )
 
 const (
@@ -24,27 +23,53 @@ const (
 )
 
 type Connection struct {
-	voiceConnection *discordgo.VoiceConnection
-	send            chan []int16
-	lock            sync.Mutex
-	sendpcm         bool
-	stopRunning     bool
-	playing         bool
-	ffmpegCmd       *exec.Cmd
-	ytdlpCmd        *exec.Cmd
+	sink        AudioSink
+	encoder     *Encoder
+	send        chan []int16
+	lock        sync.Mutex
+	sendpcm     bool
+	stopRunning bool
+	playing     bool
+	paused      bool
+	ffmpegCmd   *exec.Cmd
+	ytdlpCmd    *exec.Cmd
+	offset      time.Duration
+	frames      atomic.Int64 // frames sent to the sink
 }
 
-func NewConnection(voiceConnection *discordgo.VoiceConnection) *Connection {
+func NewConnection(sink AudioSink, encoder *Encoder) *Connection {
 	return &Connection{
-		voiceConnection: voiceConnection,
+		sink:    sink,
+		encoder: encoder,
 	}
 }
 
 func (connection *Connection) Disconnect() {
-	connection.voiceConnection.Disconnect()
+	connection.sink.Close()
 }
 
-func (connection *Connection) sendPCM(voice *discordgo.VoiceConnection, pcm <-chan []int16, paused *bool, pauseMutex *sync.Mutex) {
+// Position returns how far into the track playback is.
+func (connection *Connection) Position() time.Duration {
+	connection.lock.Lock()
+	offset := connection.offset
+	connection.lock.Unlock()
+	return offset + time.Duration(connection.frames.Load())*FRAME_SIZE*time.Second/FRAME_RATE
+}
+
+// Played returns how much of the track has been sent, leaving out the
+// offset it started from and any time spent paused.
+func (connection *Connection) Played() time.Duration {
+	return time.Duration(connection.frames.Load()) * FRAME_SIZE * time.Second / FRAME_RATE
+}
+
+// SetPaused holds or releases outgoing frames without stopping the stream.
+func (connection *Connection) SetPaused(paused bool) {
+	connection.lock.Lock()
+	defer connection.lock.Unlock()
+	connection.paused = paused
+}
+
+func (connection *Connection) sendPCM(sink AudioSink, pcm <-chan []int16) {
 	connection.lock.Lock()
 	if connection.sendpcm || pcm == nil {
 		connection.lock.Unlock()
@@ -61,71 +86,99 @@ func (connection *Connection) sendPCM(voice *discordgo.VoiceConnection, pcm <-ch
 		}
 	}()
 
-	encoder, err := gopus.NewEncoder(FRAME_RATE, CHANNELS, gopus.Audio)
-	if err != nil {
-		fmt.Println("NewEncoder error,", err)
-		return
-	}
-
 	for frame := range pcm {
 		// Pause logic
 		for {
-			pauseMutex.Lock()
-			isPaused := *paused
-			pauseMutex.Unlock()
+			connection.lock.Lock()
+			isPaused := connection.paused && !connection.stopRunning
+			connection.lock.Unlock()
 			if !isPaused {
 				break
 			}
 			time.Sleep(100 * time.Millisecond)
 		}
 
-		opus, err := encoder.Encode(frame, FRAME_SIZE, MAX_BYTES)
+		if pcmSink, ok := sink.(PCMWriter); ok {
+			if err := pcmSink.WritePCM(frame); err != nil {
+				fmt.Println("Sink write error,", err)
+				return
+			}
+			connection.frames.Add(1)
+			continue
+		}
+
+		opus, err := connection.encoder.Encode(frame)
 		if err != nil {
 			fmt.Println("Encoding error,", err)
 			return
 		}
-		if !voice.Ready || voice.OpusSend == nil {
-			fmt.Printf("Discordgo not ready for opus packets. %+v : %+v", voice.Ready, voice.OpusSend)
+		if err := sink.WriteOpus(opus); err != nil {
+			fmt.Println("Sink write error,", err)
 			return
 		}
-		voice.OpusSend <- opus
+		connection.frames.Add(1)
 	}
 
 	fmt.Println("sendPCM: channel closed, exiting")
 }
 
-func (connection *Connection) Play(youtubeURL string, paused *bool, pauseMutex *sync.Mutex) error {
+// isLocalFile reports whether source is a path on disk rather than a URL,
+// which lets headless runs play fixtures without yt-dlp.
+func isLocalFile(source string) bool {
+	if strings.Contains(source, "://") {
+		return false
+	}
+	info, err := os.Stat(source)
+	return err == nil && !info.IsDir()
+}
+
+// Play streams youtubeURL to the sink, starting offset into the track.
+func (connection *Connection) Play(youtubeURL string, offset time.Duration) error {
 	connection.lock.Lock()
 	if connection.playing {
 		connection.lock.Unlock()
 		return errors.New("song already playing")
 	}
+	if connection.stopRunning {
+		// Stopped before playback began.
+		connection.lock.Unlock()
+		return nil
+	}
 	connection.playing = true
-	connection.stopRunning = false
+	connection.offset = offset
 	connection.lock.Unlock()
 
-	// ytdlp := exec.Command("yt-dlp", "-f", "bestaudio", "-o", "-", youtubeURL)
-	ytdlp := exec.Command("yt-dlp", "-f", "bestaudio[ext=m4a]", "--no-playlist", "-o", "-", youtubeURL)
-	ffmpeg := exec.Command("ffmpeg",
-		"-re",
-		"-i", "pipe:0",
+	input := "pipe:0"
+	var ytdlp *exec.Cmd
+	if isLocalFile(youtubeURL) {
+		input = youtubeURL
+	} else {
+		// ytdlp := exec.Command("yt-dlp", "-f", "bestaudio", "-o", "-", youtubeURL)
+		ytdlp = exec.Command("yt-dlp", "-f", "bestaudio[ext=m4a]", "--no-playlist", "-o", "-", youtubeURL)
+	}
+	args := []string{"-re"}
+	if offset > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64))
+	}
+	args = append(args,
+		"-i", input,
 		"-f", "s16le",
 		"-ar", strconv.Itoa(FRAME_RATE),
 		"-ac", strconv.Itoa(CHANNELS),
 		"pipe:1",
 	)
-
-	connection.ffmpegCmd = ffmpeg
-	connection.ytdlpCmd = ytdlp
-	ytdlp.Stderr = os.Stderr
+	ffmpeg := exec.Command("ffmpeg", args...)
 	ffmpeg.Stderr = os.Stderr
 
-	ytdlpOut, err := ytdlp.StdoutPipe()
-	if err != nil {
-		fmt.Println("yt-dlp pipe error:", err)
-		return err
+	if ytdlp != nil {
+		ytdlp.Stderr = os.Stderr
+		ytdlpOut, err := ytdlp.StdoutPipe()
+		if err != nil {
+			fmt.Println("yt-dlp pipe error:", err)
+			return err
+		}
+		ffmpeg.Stdin = ytdlpOut
 	}
-	ffmpeg.Stdin = ytdlpOut
 
 	out, err := ffmpeg.StdoutPipe()
 	if err != nil {
@@ -133,17 +186,29 @@ func (connection *Connection) Play(youtubeURL string, paused *bool, pauseMutex *
 	}
 	buffer := bufio.NewReaderSize(out, 16384)
 
-	if err := ytdlp.Start(); err != nil {
-		fmt.Println("yt-dlp start error:", err)
-		return err
+	if ytdlp != nil {
+		if err := ytdlp.Start(); err != nil {
+			fmt.Println("yt-dlp start error:", err)
+			return err
+		}
 	}
 	if err := ffmpeg.Start(); err != nil {
+		if ytdlp != nil {
+			_ = ytdlp.Process.Kill()
+		}
 		return err
 	}
 
-	connection.voiceConnection.Speaking(true)
+	// Stop kills the processes from here on; until now the loop below
+	// catches a stop.
+	connection.lock.Lock()
+	connection.ffmpegCmd = ffmpeg
+	connection.ytdlpCmd = ytdlp
+	connection.lock.Unlock()
+
+	connection.sink.Speaking(true)
 	defer func() {
-		connection.voiceConnection.Speaking(false)
+		connection.sink.Speaking(false)
 		connection.lock.Lock()
 		connection.playing = false
 		connection.lock.Unlock()
@@ -157,12 +222,15 @@ func (connection *Connection) Play(youtubeURL string, paused *bool, pauseMutex *
 	sendChan := connection.send
 	connection.lock.Unlock()
 
-	go connection.sendPCM(connection.voiceConnection, sendChan, paused, pauseMutex)
+	go connection.sendPCM(connection.sink, sendChan)
 
 	for {
 		connection.lock.Lock()
 		if connection.stopRunning {
 			_ = ffmpeg.Process.Kill()
+			if ytdlp != nil {
+				_ = ytdlp.Process.Kill()
+			}
 			connection.lock.Unlock()
 			break
 		}
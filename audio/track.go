@@ -2,9 +2,11 @@ package audio
 
 import (
 	"math/rand"
-	"os/exec"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type LoopMode int
@@ -29,38 +31,210 @@ func (m LoopMode) String() string {
 }
 
 type Track struct {
-	URL      string
-	Title    string
-	Duration string
-	Uploader string
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+	Duration string `json:"duration,omitempty"`
+	Uploader string `json:"uploader,omitempty"`
+
+	// RequesterID and RequesterName identify who queued the track, and
+	// EnqueuedAt when they did.
+	RequesterID   string    `json:"requester_id,omitempty"`
+	RequesterName string    `json:"requester_name,omitempty"`
+	EnqueuedAt    time.Time `json:"enqueued_at,omitempty"`
+
+	// Autoplay marks a track picked by autoplay rather than a person.
+	Autoplay bool `json:"autoplay,omitempty"`
+
+	// Offset is where playback starts, set when resuming a track that
+	// was interrupted part way through.
+	Offset time.Duration `json:"offset,omitempty"`
+}
+
+// Length parses Duration, which yt-dlp formats as "[[h:]m:]s". It reports
+// false for live streams and tracks that have not been resolved yet.
+func (t *Track) Length() (time.Duration, bool) {
+	if t.Duration == "" {
+		return 0, false
+	}
+	var seconds int
+	for _, part := range strings.Split(t.Duration, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// Clean returns a copy of the track without its playback position,
+// requester or autoplay mark, for keeping in a playlist or favorites where
+// edits to the queue can't reach it. Whoever queues it again becomes the
+// requester.
+func (t *Track) Clean() *Track {
+	c := *t
+	c.Offset = 0
+	c.RequesterID, c.RequesterName, c.EnqueuedAt = "", "", time.Time{}
+	c.Autoplay = false
+	return &c
+}
+
+// Key identifies the track for duplicate checks, so different links to
+// the same YouTube video match.
+func (t *Track) Key() string {
+	if id := YouTubeID(t.URL); id != "" {
+		return "youtube:" + id
+	}
+	return t.URL
+}
+
+// Matches reports whether the track's title, uploader or URL contains
+// text, ignoring case.
+func (t *Track) Matches(text string) bool {
+	text = strings.ToLower(text)
+	return strings.Contains(strings.ToLower(t.Title), text) ||
+		strings.Contains(strings.ToLower(t.Uploader), text) ||
+		strings.Contains(strings.ToLower(t.URL), text)
+}
+
+// YouTubeID extracts the video ID from a YouTube or youtu.be URL, or
+// returns "" for anything else.
+func YouTubeID(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	switch strings.TrimPrefix(u.Hostname(), "www.") {
+	case "youtu.be":
+		return strings.Trim(u.Path, "/")
+	case "youtube.com", "m.youtube.com", "music.youtube.com":
+		return u.Query().Get("v")
+	}
+	return ""
 }
 
 type Queue struct {
 	Tracks []*Track
 	sync.Mutex
-	IsPlaying    bool
-	CurrentTrack *Track
-	LoopMode     LoopMode
+	current  *Track
+	LoopMode LoopMode
+	guildID  string
+	bus      *EventBus
+
+	// fair keeps Tracks interleaved by requester, so Dequeue plays
+	// requesters round-robin instead of first come, first served.
+	fair bool
+	// shuffle inserts new tracks at random positions instead of the end.
+	// Fair mode takes precedence.
+	shuffle bool
+	rng     *rand.Rand
 }
 
-func (q *Queue) Enqueue(t *Track) {
+func (q *Queue) GuildID() string {
+	return q.guildID
+}
+
+// changed publishes QueueChanged. Callers must not hold the queue lock.
+func (q *Queue) changed() {
+	if q.bus == nil {
+		return
+	}
 	q.Lock()
-	defer q.Unlock()
-	q.Tracks = append(q.Tracks, t)
+	length := len(q.Tracks)
+	q.Unlock()
+	q.bus.Publish(QueueChanged{GuildID: q.guildID, Length: length})
+}
+
+func (q *Queue) Enqueue(t *Track) {
+	q.EnqueueMultiple([]*Track{t})
 }
 
+// EnqueueMultiple adds tracks to the end of the queue or, in fair mode,
+// each to the end of its requester's next round.
 func (q *Queue) EnqueueMultiple(tracks []*Track) {
+	q.Edit(func(e *QueueEdit) { e.EnqueueMultiple(tracks) })
+}
+
+// insertFair puts t at the end of its requester's next round. Callers
+// must hold the lock.
+func (q *Queue) insertFair(t *Track) {
+	i := q.fairIndex(t.RequesterID)
+	q.Tracks = append(q.Tracks[:i], append([]*Track{t}, q.Tracks[i:]...)...)
+}
+
+// fairIndex is where a new track from requesterID goes in fair mode. A
+// requester's n-th queued track plays in round n, and it is placed after
+// every track of that round or an earlier one. The playing track counts
+// as its requester's first round. Callers must hold the lock.
+func (q *Queue) fairIndex(requesterID string) int {
+	rounds := make(map[string]int)
+	if q.current != nil && q.LoopMode != LoopAll {
+		rounds[q.current.RequesterID]++
+	}
+	round := 0
+	for _, t := range q.Tracks {
+		if t.RequesterID == requesterID {
+			round++
+		}
+	}
+	round += rounds[requesterID]
+
+	index := 0
+	for i, t := range q.Tracks {
+		if rounds[t.RequesterID] <= round {
+			index = i + 1
+		}
+		rounds[t.RequesterID]++
+	}
+	return index
+}
+
+// SetFair turns fair mode on or off. Turning it on interleaves the tracks
+// already queued.
+func (q *Queue) SetFair(fair bool) {
+	q.Lock()
+	reorder := fair && !q.fair && len(q.Tracks) > 0
+	q.fair = fair
+	if reorder {
+		tracks := q.Tracks
+		q.Tracks = nil
+		for _, t := range tracks {
+			q.insertFair(t)
+		}
+	}
+	q.Unlock()
+
+	if reorder {
+		q.changed()
+	}
+}
+
+func (q *Queue) Fair() bool {
 	q.Lock()
 	defer q.Unlock()
-	q.Tracks = append(q.Tracks, tracks...)
+	return q.fair
 }
 
+// PushFront puts tracks at the head of the queue, in order.
+func (q *Queue) PushFront(tracks ...*Track) {
+	q.Edit(func(e *QueueEdit) { e.PushFront(tracks...) })
+}
+
+// Requeue puts the current track back at the head of the queue, to start
+// from offset when it plays again.
+func (q *Queue) Requeue(offset time.Duration) {
+	q.Edit(func(e *QueueEdit) { e.Requeue(offset) })
+}
+
+// Dequeue takes the next track to play. In fair mode the queue is already
+// in round-robin order, so this is still the head of Tracks.
 func (q *Queue) Dequeue() *Track {
+	defer q.changed()
 	q.Lock()
 	defer q.Unlock()
 
-	if q.CurrentTrack != nil && q.LoopMode == LoopOne {
-		return q.CurrentTrack
+	if q.current != nil && q.LoopMode == LoopOne {
+		return q.current
 	}
 
 	if len(q.Tracks) == 0 {
@@ -74,10 +248,29 @@ func (q *Queue) Dequeue() *Track {
 		q.Tracks = append(q.Tracks, track)
 	}
 
-	q.CurrentTrack = track
+	q.current = track
 	return track
 }
 
+// Current returns the track being played, if any.
+func (q *Queue) Current() *Track {
+	q.Lock()
+	defer q.Unlock()
+	return q.current
+}
+
+func (q *Queue) SetCurrent(t *Track) {
+	q.Lock()
+	defer q.Unlock()
+	q.current = t
+}
+
+// Replace swaps every occurrence of old, including the current track,
+// for t. Used once a track's metadata has been resolved.
+func (q *Queue) Replace(old, t *Track) {
+	q.Edit(func(e *QueueEdit) { e.Replace(old, t) })
+}
+
 func (q *Queue) List() []*Track {
 	q.Lock()
 	defer q.Unlock()
@@ -85,13 +278,11 @@ func (q *Queue) List() []*Track {
 }
 
 func (q *Queue) Clear() {
-	q.Lock()
-	defer q.Unlock()
-	q.Tracks = nil
-	q.CurrentTrack = nil
+	q.Edit(func(e *QueueEdit) { e.Clear() })
 }
 
 func (q *Queue) SetLoopMode(mode LoopMode) {
+	defer q.changed()
 	q.Lock()
 	defer q.Unlock()
 	q.LoopMode = mode
@@ -104,84 +295,91 @@ func (q *Queue) GetLoopMode() LoopMode {
 }
 
 func (q *Queue) ToggleLoopMode() LoopMode {
+	defer q.changed()
 	q.Lock()
 	defer q.Unlock()
 	q.LoopMode = (q.LoopMode + 1) % 3
 	return q.LoopMode
 }
 
-func ExtractPlaylistTracks(playlistURL string) ([]*Track, error) {
-	cmd := exec.Command("yt-dlp", "--flat-playlist", "--print", "%(title)s|%(url)s", playlistURL)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	var tracks []*Track
-	for _, line := range lines {
-		parts := strings.SplitN(line, "|", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		title, url := parts[0], parts[1]
-		// Fix: Only prepend if not already a full URL
-		if !strings.HasPrefix(url, "http") {
-			url = "https://www.youtube.com/watch?v=" + url
-		}
-		tracks = append(tracks, &Track{
-			Title: title,
-			URL:   url,
-		})
-	}
-	return tracks, nil
-}
-
 // Shuffle randomly shuffles the queue (excluding CurrentTrack)
 func (q *Queue) Shuffle() {
-	q.Lock()
-	defer q.Unlock()
-	rand.Shuffle(len(q.Tracks), func(i, j int) {
-		q.Tracks[i], q.Tracks[j] = q.Tracks[j], q.Tracks[i]
-	})
+	q.Edit(func(e *QueueEdit) { e.Shuffle() })
 }
 
 // Remove deletes a track by 0-based index
 func (q *Queue) Remove(index int) bool {
-	q.Lock()
-	defer q.Unlock()
-	if index < 0 || index >= len(q.Tracks) {
-		return false
-	}
-	q.Tracks = append(q.Tracks[:index], q.Tracks[index+1:]...)
-	return true
+	var ok bool
+	q.Edit(func(e *QueueEdit) { ok = e.Remove(index) })
+	return ok
 }
 
 // Insert adds a track at a specific 0-based index
 func (q *Queue) Insert(index int, track *Track) bool {
-	q.Lock()
-	defer q.Unlock()
-	if index < 0 || index > len(q.Tracks) {
-		return false
-	}
-	q.Tracks = append(q.Tracks[:index], append([]*Track{track}, q.Tracks[index:]...)...)
-	return true
+	var ok bool
+	q.Edit(func(e *QueueEdit) { ok = e.Insert(index, track) })
+	return ok
 }
 
 // Move reorders a track from one position to another
 func (q *Queue) Move(from, to int) bool {
-	q.Lock()
-	defer q.Unlock()
+	var ok bool
+	q.Edit(func(e *QueueEdit) { ok = e.Move(from, to) })
+	return ok
+}
 
-	if from < 0 || from >= len(q.Tracks) || to < 0 || to >= len(q.Tracks) {
-		return false
-	}
+// RemoveRange deletes the tracks from index from to index to, both 0-based
+// and inclusive, and returns how many it removed.
+func (q *Queue) RemoveRange(from, to int) int {
+	var removed int
+	q.Edit(func(e *QueueEdit) { removed = e.RemoveRange(from, to) })
+	return removed
+}
+
+// RemoveWhere deletes every track match reports true for and returns how
+// many it removed.
+func (q *Queue) RemoveWhere(match func(t *Track) bool) int {
+	var removed int
+	q.Edit(func(e *QueueEdit) { removed = e.RemoveWhere(match) })
+	return removed
+}
+
+// Dedupe deletes every track that repeats an earlier one, or the current
+// track, and returns how many it removed.
+func (q *Queue) Dedupe() int {
+	var removed int
+	q.Edit(func(e *QueueEdit) { removed = e.Dedupe() })
+	return removed
+}
+
+// RemoveRangeWhere deletes the tracks match reports true for from index
+// from to index to, both 0-based and inclusive, and returns how many it
+// removed.
+func (q *Queue) RemoveRangeWhere(from, to int, match func(t *Track) bool) int {
+	var removed int
+	q.Edit(func(e *QueueEdit) { removed = e.RemoveRangeWhere(from, to, match) })
+	return removed
+}
+
+// DedupeWhere is Dedupe, but only deletes the repeats match reports true
+// for.
+func (q *Queue) DedupeWhere(match func(t *Track) bool) int {
+	var removed int
+	q.Edit(func(e *QueueEdit) { removed = e.DedupeWhere(match) })
+	return removed
+}
 
-	track := q.Tracks[from]
-	// Remove from old position
-	q.Tracks = append(q.Tracks[:from], q.Tracks[from+1:]...)
-	// Insert at new position
-	q.Tracks = append(q.Tracks[:to], append([]*Track{track}, q.Tracks[to:]...)...)
+// Find returns the 0-based positions of tracks that match text.
+func (q *Queue) Find(text string) []int {
+	q.Lock()
+	defer q.Unlock()
+	return (&QueueEdit{q: q}).Find(text)
+}
 
-	return true
+// DropBefore discards every track before the 0-based index, so that track
+// plays next.
+func (q *Queue) DropBefore(index int) bool {
+	var ok bool
+	q.Edit(func(e *QueueEdit) { ok = e.DropBefore(index) })
+	return ok
 }
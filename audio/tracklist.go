@@ -0,0 +1,207 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TrackListFormat is a file format queues can be exported to and imported from.
+type TrackListFormat string
+
+const (
+	FormatM3U  TrackListFormat = "m3u"
+	FormatXSPF TrackListFormat = "xspf"
+	FormatJSON TrackListFormat = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown track list format")
+
+// FormatDuration formats d the way yt-dlp's duration_string does, so a
+// parsed Duration can be written back unchanged.
+func FormatDuration(d time.Duration) string {
+	seconds := int(d.Seconds())
+	switch {
+	case seconds >= 3600:
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	case seconds >= 60:
+		return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+	default:
+		return strconv.Itoa(seconds)
+	}
+}
+
+// exportable copies a track's metadata, leaving out who queued it and any
+// playback state.
+func exportable(t *Track) *Track {
+	return &Track{URL: t.URL, Title: t.Title, Duration: t.Duration, Uploader: t.Uploader}
+}
+
+// ExportTracks encodes tracks' URLs and metadata in format.
+func ExportTracks(tracks []*Track, format TrackListFormat) ([]byte, error) {
+	switch format {
+	case FormatM3U:
+		return exportM3U(tracks), nil
+	case FormatXSPF:
+		return exportXSPF(tracks)
+	case FormatJSON:
+		out := make([]*Track, len(tracks))
+		for i, t := range tracks {
+			out[i] = exportable(t)
+		}
+		return json.MarshalIndent(out, "", "  ")
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ImportTracks decodes a track list exported by ExportTracks or written by
+// hand. The format is taken from the file name's extension, or guessed
+// from the content, and anything unrecognised is read as one URL per line.
+func ImportTracks(name string, data []byte) ([]*Track, error) {
+	format := TrackListFormat(strings.ToLower(strings.TrimPrefix(name[strings.LastIndex(name, ".")+1:], ".")))
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case format == FormatJSON || bytes.HasPrefix(trimmed, []byte("[")):
+		return importJSON(trimmed)
+	case format == FormatXSPF || bytes.HasPrefix(trimmed, []byte("<")):
+		return importXSPF(trimmed)
+	default:
+		return importM3U(trimmed), nil
+	}
+}
+
+// importJSON reads a JSON array of tracks, skipping entries without a URL.
+func importJSON(data []byte) ([]*Track, error) {
+	var entries []*Track
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	var tracks []*Track
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		t := exportable(entry)
+		t.URL = strings.TrimSpace(t.URL)
+		if t.URL == "" {
+			continue
+		}
+		if t.Title == "" {
+			t.Title = t.URL
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, nil
+}
+
+// exportM3U writes an extended M3U playlist. The uploader goes in the
+// #EXTART tag so the title can be read back as is.
+func exportM3U(tracks []*Track) []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	for _, t := range tracks {
+		seconds := -1
+		if length, ok := t.Length(); ok {
+			seconds = int(length.Seconds())
+		}
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n", seconds, oneLine(t.Title))
+		if t.Uploader != "" {
+			fmt.Fprintf(&b, "#EXTART:%s\n", oneLine(t.Uploader))
+		}
+		b.WriteString(t.URL + "\n")
+	}
+	return b.Bytes()
+}
+
+// importM3U reads an M3U playlist, which covers a plain list of URLs too.
+func importM3U(data []byte) []*Track {
+	var tracks []*Track
+	next := &Track{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info := strings.TrimPrefix(line, "#EXTINF:")
+			seconds, title, _ := strings.Cut(info, ",")
+			if n, err := strconv.Atoi(seconds); err == nil && n >= 0 {
+				next.Duration = FormatDuration(time.Duration(n) * time.Second)
+			}
+			next.Title = title
+		case strings.HasPrefix(line, "#EXTART:"):
+			next.Uploader = strings.TrimPrefix(line, "#EXTART:")
+		case strings.HasPrefix(line, "#"):
+		default:
+			next.URL = line
+			if next.Title == "" {
+				next.Title = line
+			}
+			tracks = append(tracks, next)
+			next = &Track{}
+		}
+	}
+	return tracks
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Duration int64  `xml:"duration,omitempty"` // milliseconds
+}
+
+func exportXSPF(tracks []*Track) ([]byte, error) {
+	playlist := xspfPlaylist{Version: "1"}
+	for _, t := range tracks {
+		xt := xspfTrack{Location: t.URL, Title: t.Title, Creator: t.Uploader}
+		if length, ok := t.Length(); ok {
+			xt.Duration = length.Milliseconds()
+		}
+		playlist.Tracks = append(playlist.Tracks, xt)
+	}
+	out, err := xml.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func importXSPF(data []byte) ([]*Track, error) {
+	var playlist xspfPlaylist
+	if err := xml.Unmarshal(data, &playlist); err != nil {
+		return nil, err
+	}
+	var tracks []*Track
+	for _, xt := range playlist.Tracks {
+		t := &Track{URL: strings.TrimSpace(xt.Location), Title: xt.Title, Uploader: xt.Creator}
+		if t.URL == "" {
+			continue
+		}
+		if t.Title == "" {
+			t.Title = t.URL
+		}
+		if xt.Duration > 0 {
+			t.Duration = FormatDuration(time.Duration(xt.Duration) * time.Millisecond)
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, nil
+}
@@ -0,0 +1,95 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func exportedTracks() []*Track {
+	return []*Track{
+		{URL: "https://youtu.be/a", Title: "First, with a comma", Duration: "3:05", Uploader: "Someone"},
+		{URL: "https://youtu.be/b", Title: "Long one", Duration: "1:02:03"},
+		{URL: "https://example.com/live", Title: "Live stream", Uploader: "Radio"},
+		{URL: "https://youtu.be/c", Title: "Short", Duration: "42"},
+	}
+}
+
+func TestTrackListRoundTrip(t *testing.T) {
+	for _, format := range []TrackListFormat{FormatM3U, FormatXSPF, FormatJSON} {
+		tracks := exportedTracks()
+		// Who queued a track and where it was stopped aren't exported.
+		tracks[0].RequesterID = "alice"
+		tracks[0].EnqueuedAt = time.Now()
+		tracks[1].Offset = time.Minute
+
+		data, err := ExportTracks(tracks, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		got, err := ImportTracks("queue."+string(format), data)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, exportedTracks()) {
+			t.Errorf("%s round trip:\n got %+v\nwant %+v", format, got, exportedTracks())
+		}
+
+		// Without an extension the format is guessed from the content.
+		if guessed, err := ImportTracks("queue", data); err != nil || !reflect.DeepEqual(guessed, got) {
+			t.Errorf("%s without an extension: got %+v, %v", format, guessed, err)
+		}
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	if _, err := ExportTracks(exportedTracks(), "pls"); err != ErrUnknownFormat {
+		t.Errorf("got %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestImportPlainURLs(t *testing.T) {
+	got, err := ImportTracks("list.txt", []byte("# a comment\nhttps://youtu.be/a\n\n  https://youtu.be/b  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Track{
+		{URL: "https://youtu.be/a", Title: "https://youtu.be/a"},
+		{URL: "https://youtu.be/b", Title: "https://youtu.be/b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestImportMalformed(t *testing.T) {
+	for _, tt := range []struct {
+		name, data string
+		want       []string // URLs
+		err        bool
+	}{
+		{name: "q.json", data: `[null]`},
+		{name: "q.json", data: `[{}]`},
+		{name: "q.json", data: `[{"url": "  "}, null, {"url": "https://youtu.be/a"}]`, want: []string{"https://youtu.be/a"}},
+		{name: "q.json", data: `[{"url": 1}]`, err: true},
+		{name: "q.json", data: `{"url": "https://youtu.be/a"}`, err: true},
+		{name: "q.json", data: `[`, err: true},
+		{name: "q.xspf", data: `<playlist`, err: true},
+		{name: "q.xspf", data: `<playlist xmlns="http://xspf.org/ns/0/"><trackList><track><title>No location</title></track></trackList></playlist>`},
+		{name: "q.m3u", data: "#EXTM3U\n#EXTINF:oops,Title\nhttps://youtu.be/a\n#EXTINF:12,Dangling", want: []string{"https://youtu.be/a"}},
+		{name: "q.m3u", data: ""},
+	} {
+		got, err := ImportTracks(tt.name, []byte(tt.data))
+		if (err != nil) != tt.err {
+			t.Errorf("%s %q: error = %v", tt.name, tt.data, err)
+			continue
+		}
+		var urls []string
+		for _, track := range got {
+			urls = append(urls, track.URL)
+		}
+		if !reflect.DeepEqual(urls, tt.want) {
+			t.Errorf("%s %q: got %q, want %q", tt.name, tt.data, urls, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/settings"
+)
+
+// Recommend picks a track to follow last when the guild has autoplay on.
+// It tries YouTube's mix for last first, then tracks that followed last in
+// the guild's history, skipping anything played within the guild's repeat
+// window or already queued. It is the players' Recommender.
+func (s *Services) Recommend(guildID string, last *audio.Track) *audio.Track {
+	gs := s.Settings.Get(guildID)
+	if !gs.Autoplay {
+		return nil
+	}
+
+	history := s.History.Get(guildID).Recent(0, audio.MAX_HISTORY)
+	// Keyed by Track.Key, since the mix lists videos as watch URLs that
+	// may not match how they were queued.
+	avoid := map[string]bool{last.Key(): true}
+	for i, entry := range history {
+		if i == gs.RepeatWindow() {
+			break
+		}
+		avoid[entry.Track.Key()] = true
+	}
+	for _, t := range s.QueueManager.Get(guildID).List() {
+		avoid[t.Key()] = true
+	}
+
+	for _, t := range relatedTracks(last) {
+		if !avoid[t.Key()] {
+			return autoplayed(t)
+		}
+	}
+	if t := historyNeighbour(history, last, avoid); t != nil {
+		return autoplayed(t)
+	}
+	return nil
+}
+
+func autoplayed(t *audio.Track) *audio.Track {
+	c := *t
+	c.Offset = 0
+	c.RequesterID, c.RequesterName = "", ""
+	c.Autoplay = true
+	return &c
+}
+
+// relatedTracks lists YouTube's auto-generated mix for t, if t is a
+// YouTube video.
+func relatedTracks(t *audio.Track) []*audio.Track {
+	id := audio.YouTubeID(t.URL)
+	if id == "" {
+		return nil
+	}
+	tracks, err := audio.ExtractPlaylistTracks("https://www.youtube.com/watch?v="+id+"&list=RD"+id, audio.PlaylistOptions{})
+	if err != nil {
+		fmt.Println("Failed to fetch related tracks for", t.URL+":", err)
+		return nil
+	}
+	return tracks
+}
+
+// historyNeighbour scores tracks by how often they were played right after
+// (or, counting half as much, right before) last, and returns the best one
+// whose key is not in avoid. history is newest first.
+func historyNeighbour(history []audio.HistoryEntry, last *audio.Track, avoid map[string]bool) *audio.Track {
+	scores := make(map[string]int)
+	tracks := make(map[string]*audio.Track)
+	for i, entry := range history {
+		if entry.Track.Key() != last.Key() {
+			continue
+		}
+		if i > 0 {
+			after := history[i-1].Track
+			scores[after.Key()] += 2
+			tracks[after.Key()] = after
+		}
+		if i+1 < len(history) {
+			before := history[i+1].Track
+			scores[before.Key()]++
+			tracks[before.Key()] = before
+		}
+	}
+
+	var best *audio.Track
+	bestScore := 0
+	for k, score := range scores {
+		if avoid[k] {
+			continue
+		}
+		if score > bestScore || (score == bestScore && best != nil && k < best.Key()) {
+			best, bestScore = tracks[k], score
+		}
+	}
+	return best
+}
+
+// Autoplay turns autoplay on or off for the guild.
+func (cmd *BotCommand) Autoplay(state string) {
+	enabled, ok := parseOnOff(state)
+	if !ok {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "Usage: `>autoplay on|off`")
+		return
+	}
+
+	cmd.Settings.Update(cmd.Message.GuildID, func(gs *settings.GuildSettings) { gs.Autoplay = enabled })
+	if enabled {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "📻 Autoplay is on. I'll keep playing related tracks when the queue runs out.")
+	} else {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "📻 Autoplay is off.")
+	}
+}
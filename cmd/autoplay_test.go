@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"musicbot/audio"
+	"musicbot/settings"
+	"testing"
+	"time"
+)
+
+func TestRecommendSkipsRecentAndQueuedTracks(t *testing.T) {
+	// The mix lists the seed first, then videos by bare ID, which become
+	// watch URLs unlike the youtu.be links they were queued with.
+	fakeTool(t, "yt-dlp", `
+echo 'seed|3:00|Uploader|Seed'
+echo 'played|3:00|Uploader|Played'
+echo 'queued|3:00|Uploader|Queued'
+echo 'fresh|3:00|Uploader|Fresh'
+`)
+	s := newTestServices(t)
+	s.Settings.Update("guild", func(gs *settings.GuildSettings) { gs.Autoplay = true })
+	s.History.Get("guild").Add(audio.HistoryEntry{Track: &audio.Track{URL: "https://youtu.be/played"}, PlayedAt: time.Now()})
+	s.QueueManager.Get("guild").Enqueue(&audio.Track{URL: "https://youtu.be/queued"})
+
+	got := s.Recommend("guild", &audio.Track{URL: "https://youtu.be/seed"})
+	if got == nil || got.Key() != "youtube:fresh" {
+		t.Fatalf("recommended %v, want the fresh video", got)
+	}
+	if !got.Autoplay {
+		t.Error("recommended track isn't marked as autoplayed")
+	}
+}
+
+func TestRecommendWithoutRepeatWindow(t *testing.T) {
+	fakeTool(t, "yt-dlp", `
+echo 'seed|3:00|Uploader|Seed'
+echo 'played|3:00|Uploader|Played'
+`)
+	s := newTestServices(t)
+	none := 0
+	s.Settings.Update("guild", func(gs *settings.GuildSettings) { gs.Autoplay, gs.AutoplayWindow = true, &none })
+	s.History.Get("guild").Add(audio.HistoryEntry{Track: &audio.Track{URL: "https://youtu.be/played"}, PlayedAt: time.Now()})
+
+	if got := s.Recommend("guild", &audio.Track{URL: "https://youtu.be/seed"}); got == nil || got.Key() != "youtube:played" {
+		t.Fatalf("recommended %v with no repeat window, want the played video", got)
+	}
+	if got := (settings.GuildSettings{}).RepeatWindow(); got != settings.DEFAULT_AUTOPLAY_WINDOW {
+		t.Errorf("unset repeat window = %d, want the default", got)
+	}
+}
+
+func TestHistoryNeighbourMatchesByKey(t *testing.T) {
+	entry := func(url string) audio.HistoryEntry {
+		return audio.HistoryEntry{Track: &audio.Track{URL: url}}
+	}
+	// Newest first: b followed the seed, played under another link.
+	history := []audio.HistoryEntry{
+		entry("https://youtu.be/b"),
+		entry("https://www.youtube.com/watch?v=seed"),
+		entry("https://youtu.be/a"),
+	}
+	last := &audio.Track{URL: "https://youtu.be/seed"}
+
+	if got := historyNeighbour(history, last, map[string]bool{}); got == nil || got.Key() != "youtube:b" {
+		t.Errorf("picked %v, want b", got)
+	}
+	if got := historyNeighbour(history, last, map[string]bool{"youtube:b": true}); got == nil || got.Key() != "youtube:a" {
+		t.Errorf("picked %v with b avoided, want a", got)
+	}
+}
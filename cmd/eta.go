@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"musicbot/audio"
+	"time"
+)
+
+// formatETA describes an Estimate roughly, e.g. "in ~12m" or "in ~1h05m".
+func formatETA(e audio.Estimate) string {
+	switch {
+	case !e.Known:
+		return "in ?"
+	case e.In < time.Minute:
+		return "in <1m"
+	default:
+		return "in ~" + formatHours(e.In.Round(time.Minute))
+	}
+}
+
+// playsIn tells the author when t, which they just queued, should start.
+// It says nothing when t is up straight away or its start is unknown.
+func (cmd *BotCommand) playsIn(t *audio.Track) string {
+	guildID := cmd.Message.GuildID
+	queue := cmd.QueueManager.Get(guildID)
+	if queue.Current() == nil {
+		return ""
+	}
+
+	timeline := queue.Timeline(cmd.Players.Get(guildID).Position())
+	for i, queued := range queue.List() {
+		if queued != t || i >= len(timeline.Starts) {
+			continue
+		}
+		if start := timeline.Starts[i]; start.Known {
+			return "\n⏳ It plays " + formatETA(start) + "."
+		}
+	}
+	return ""
+}
+
+// resolveQueued fills in the metadata of t, which is waiting in guildID's
+// queue, so the tracks after it get a start before it plays. If it has
+// started playing already, the player resolves it instead.
+func (s *Services) resolveQueued(guildID string, t *audio.Track) {
+	resolved := *t
+	extractMetadata(&resolved)
+	s.QueueManager.Get(guildID).Edit(func(e *audio.QueueEdit) {
+		if e.Current() != t {
+			e.Replace(t, &resolved)
+			s.Journals.Get(guildID).Replace(t, &resolved)
+		}
+	})
+}
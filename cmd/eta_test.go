@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"musicbot/audio"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestFormatETA(t *testing.T) {
+	for _, tt := range []struct {
+		estimate audio.Estimate
+		want     string
+	}{
+		{audio.Estimate{In: time.Hour}, "in ?"},
+		{audio.Estimate{In: 0, Known: true}, "in <1m"},
+		{audio.Estimate{In: 59 * time.Second, Known: true}, "in <1m"},
+		{audio.Estimate{In: 90 * time.Second, Known: true}, "in ~2m"},
+		{audio.Estimate{In: 59*time.Minute + 29*time.Second, Known: true}, "in ~59m"},
+		{audio.Estimate{In: 59*time.Minute + 31*time.Second, Known: true}, "in ~1h00m"},
+		{audio.Estimate{In: 2*time.Hour + 5*time.Minute, Known: true}, "in ~2h05m"},
+		// The same format as listening time in >stats.
+		{audio.Estimate{In: 12*time.Hour + 4*time.Minute + 31*time.Second, Known: true}, "in ~" + formatHours(12*time.Hour+5*time.Minute)},
+	} {
+		if got := formatETA(tt.estimate); got != tt.want {
+			t.Errorf("formatETA(%+v) = %q, want %q", tt.estimate, got, tt.want)
+		}
+	}
+}
+
+func TestPlaysIn(t *testing.T) {
+	s := newTestServices(t)
+	cmd := NewBotCommand(nil, &discordgo.MessageCreate{Message: &discordgo.Message{GuildID: "guild"}}, s)
+	queue := s.QueueManager.Get("guild")
+
+	first := &audio.Track{URL: "first", Duration: "4:00"}
+	if got := cmd.playsIn(first); got != "" {
+		t.Errorf("with nothing playing, got %q", got)
+	}
+
+	queue.EnqueueMultiple([]*audio.Track{{URL: "now", Duration: "5:00"}, first})
+	queue.Dequeue()
+	if got := cmd.playsIn(first); got != "\n⏳ It plays in ~5m." {
+		t.Errorf("got %q", got)
+	}
+
+	live := &audio.Track{URL: "live"}
+	after := &audio.Track{URL: "after", Duration: "1:00"}
+	queue.EnqueueMultiple([]*audio.Track{live, after})
+	if got := cmd.playsIn(live); got != "\n⏳ It plays in ~9m." {
+		t.Errorf("got %q", got)
+	}
+	if got := cmd.playsIn(after); got != "" {
+		t.Errorf("after a live stream, got %q", got)
+	}
+}
+
+func TestResolveQueued(t *testing.T) {
+	fakeTool(t, "yt-dlp", "echo 'Later|2:00|Uploader'")
+	s := newTestServices(t)
+	queue := s.QueueManager.Get("guild")
+	queue.EnqueueMultiple([]*audio.Track{{URL: "now", Duration: "5:00"}})
+	queue.Dequeue()
+
+	later := &audio.Track{URL: "later", Title: "later"}
+	after := &audio.Track{URL: "after", Duration: "1:00"}
+	queue.EnqueueMultiple([]*audio.Track{later, after})
+	s.resolveQueued("guild", later)
+
+	if got := queue.List()[0]; got.Title != "Later" || got.Duration != "2:00" {
+		t.Errorf("queued %+v, want it resolved", got)
+	}
+	if start := queue.Timeline(0).Starts[1]; !start.Known || start.In != 7*time.Minute {
+		t.Errorf("the track after it starts %+v, want in 7m", start)
+	}
+}
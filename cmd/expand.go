@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/vc"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// expandBatch is how many playlist entries are queued at a time once the
+// first one is playing.
+const expandBatch = 25
+
+// parsePlaylistOptions reads the words after a playlist URL: "shuffle" and
+// a range of entries like "10-20", "10-" or "-20".
+func parsePlaylistOptions(words []string) (audio.PlaylistOptions, bool) {
+	var opts audio.PlaylistOptions
+	for _, word := range words {
+		if word == "shuffle" {
+			opts.Shuffle = true
+			continue
+		}
+		from, to, ok := strings.Cut(word, "-")
+		if !ok || from == "" && to == "" {
+			return opts, false
+		}
+		var err error
+		if from != "" {
+			if opts.Start, err = strconv.Atoi(from); err != nil || opts.Start < 1 {
+				return opts, false
+			}
+		}
+		if to != "" {
+			if opts.End, err = strconv.Atoi(to); err != nil || opts.End < max(1, opts.Start) {
+				return opts, false
+			}
+		}
+	}
+	return opts, true
+}
+
+// playlistLimit is how many entries of a playlist the author can queue at
+// once: the guild's playlist cap, or its playlist limit for non-DJs if lower.
+func (cmd *BotCommand) playlistLimit() int {
+	gs := cmd.Settings.Get(cmd.Message.GuildID)
+	limit := gs.ExpandCap()
+	if n := gs.Limits.MaxPlaylist; n > 0 && n < limit && !cmd.isDJ() {
+		limit = n
+	}
+	return limit
+}
+
+// expansion is the playlists being expanded in a guild.
+type expansion struct {
+	stop    chan struct{} // closed when the guild's playback is stopped
+	running int
+}
+
+// startExpansion registers a playlist expansion in the guild. It returns
+// a channel that is closed when the guild's playback is stopped, to end
+// the expansion, and a func to call once it has ended.
+func (s *Services) startExpansion(guildID string) (<-chan struct{}, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	x, ok := s.expansions[guildID]
+	if !ok {
+		x = &expansion{stop: make(chan struct{})}
+		s.expansions[guildID] = x
+	}
+	x.running++
+	return x.stop, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		x.running--
+		if x.running == 0 && s.expansions[guildID] == x {
+			delete(s.expansions, guildID)
+		}
+	}
+}
+
+// CancelExpansions stops queuing the rest of any playlists being expanded
+// in the guild.
+func (s *Services) CancelExpansions(guildID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if x, ok := s.expansions[guildID]; ok {
+		close(x.stop)
+		delete(s.expansions, guildID)
+	}
+}
+
+// LeaveIdle leaves the guild's voice channel once its player has run out
+// of tracks. It stays while a playlist is still being expanded, since more
+// tracks are on their way. It reports whether it left.
+func (s *Services) LeaveIdle(guildID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expansions[guildID] != nil || s.Players.Get(guildID).State() != audio.StateIdle {
+		return false
+	}
+	if _, ok := s.VoiceManager.Get(guildID); !ok {
+		return false
+	}
+	s.Encoders.Release(guildID)
+	_ = s.VoiceManager.Leave(guildID)
+	return true
+}
+
+// expandPlaylist queues a playlist's entries as yt-dlp lists them, and
+// starts playback as soon as the first is queued. If the player runs dry
+// before the next batch lands, the batch starts it again.
+func (cmd *BotCommand) expandPlaylist(voice *vc.Voice, playlistURL string, opts audio.PlaylistOptions) {
+	// Watch URLs opened from inside a playlist say where they were.
+	if u, err := url.Parse(playlistURL); err == nil && opts.Start == 0 && opts.End == 0 && !opts.Shuffle {
+		if index, err := strconv.Atoi(u.Query().Get("index")); err == nil && index > 1 {
+			opts.Start = index
+		}
+	}
+	opts.Limit = cmd.playlistLimit()
+
+	guildID := cmd.Message.GuildID
+	stop, done := cmd.startExpansion(guildID)
+	stream, errs := audio.StreamPlaylistTracks(playlistURL, opts, stop)
+
+	var batch []*audio.Track
+	var refusals []string
+	listed, added := 0, 0
+	flush := func() {
+		// The whole playlist is one change to undo: the batch that starts
+		// it is journaled, and the rest join it.
+		action := "playlist"
+		if added > 0 {
+			action = ""
+		}
+		queued, refused := cmd.enqueueAs(action, batch, true)
+		batch = nil
+		if refused != "" && (len(refusals) == 0 || refusals[len(refusals)-1] != refused) {
+			refusals = append(refusals, refused)
+		}
+		if len(queued) == 0 {
+			return
+		}
+		if added == 0 {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "📜 Loading the playlist, starting with "+queued[0].Title+"…")
+		}
+		added += len(queued)
+		select {
+		case <-stop:
+		default:
+			// The player may have gone idle waiting for this batch.
+			cmd.startPlayback(voice)
+		}
+	}
+	defer func() {
+		done()
+		if added > 0 && cmd.LeaveIdle(guildID) {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "👋 Finished playback. Left the voice channel.")
+		}
+	}()
+
+	for track := range stream {
+		listed++
+		batch = append(batch, track)
+		if added == 0 || len(batch) >= expandBatch {
+			flush()
+		}
+	}
+	select {
+	case <-stop:
+		if added > 0 {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("📜 Stopped loading the playlist after %d tracks.", added))
+		}
+		return
+	default:
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+
+	err := <-errs
+	switch {
+	case listed == 0:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Failed to extract playlist.")
+		return
+	case added == 0:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, refusals[len(refusals)-1])
+		return
+	}
+
+	msg := fmt.Sprintf("📜 Enqueued %d tracks from the playlist.", added)
+	if err == audio.ErrPastLimit {
+		msg += fmt.Sprintf(" Stopped at the limit of %d tracks.", opts.Limit)
+	} else if err != nil {
+		msg += " Some of it couldn't be loaded."
+	}
+	for _, refused := range refusals {
+		msg += "\n" + refused
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
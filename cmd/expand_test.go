@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"musicbot/audio"
+	"strings"
+	"testing"
+)
+
+func TestParsePlaylistOptions(t *testing.T) {
+	for _, tt := range []struct {
+		words string
+		want  audio.PlaylistOptions
+		ok    bool
+	}{
+		{"", audio.PlaylistOptions{}, true},
+		{"shuffle", audio.PlaylistOptions{Shuffle: true}, true},
+		{"10-20", audio.PlaylistOptions{Start: 10, End: 20}, true},
+		{"10-", audio.PlaylistOptions{Start: 10}, true},
+		{"-20", audio.PlaylistOptions{End: 20}, true},
+		{"5-5 shuffle", audio.PlaylistOptions{Start: 5, End: 5, Shuffle: true}, true},
+		{"-", audio.PlaylistOptions{}, false},
+		{"20-10", audio.PlaylistOptions{}, false},
+		{"0-10", audio.PlaylistOptions{}, false},
+		{"-0", audio.PlaylistOptions{}, false},
+		{"a-b", audio.PlaylistOptions{}, false},
+		{"10", audio.PlaylistOptions{}, false},
+		{"random", audio.PlaylistOptions{}, false},
+	} {
+		got, ok := parsePlaylistOptions(strings.Fields(tt.words))
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("parsePlaylistOptions(%q) = %+v, %v, want %+v, %v", tt.words, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExpansions(t *testing.T) {
+	s := newTestServices(t)
+	expanding := func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.expansions["guild"] != nil
+	}
+
+	stop, done := s.startExpansion("guild")
+	again, doneAgain := s.startExpansion("guild")
+	if stop != again {
+		t.Error("expansions in one guild got different stop channels")
+	}
+	done()
+	if !expanding() {
+		t.Error("not expanding with one expansion still running")
+	}
+	if s.LeaveIdle("guild") {
+		t.Error("left voice while a playlist was expanding")
+	}
+
+	s.CancelExpansions("guild")
+	select {
+	case <-stop:
+	default:
+		t.Error("cancelling didn't close the stop channel")
+	}
+	if expanding() {
+		t.Error("still expanding after cancelling")
+	}
+
+	// One that ends after being cancelled leaves later ones alone.
+	next, doneNext := s.startExpansion("guild")
+	doneAgain()
+	if !expanding() || next == stop {
+		t.Error("an old expansion ending ended a new one")
+	}
+	doneNext()
+	if expanding() {
+		t.Error("still expanding after every expansion ended")
+	}
+}
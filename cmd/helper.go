@@ -1,9 +1,11 @@
 package commands
 
 import (
+	"fmt"
 	"musicbot/audio"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 func extractMetadata(t *audio.Track) {
@@ -18,3 +20,20 @@ func extractMetadata(t *audio.Track) {
 		}
 	}
 }
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// formatHours describes a long duration in whole minutes, e.g. "42m" or
+// "12h05m".
+func formatHours(d time.Duration) string {
+	minutes := int(d.Minutes())
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
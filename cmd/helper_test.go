@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"musicbot/store"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// newTestServices returns services persisting to a temporary store.
+func newTestServices(t *testing.T) *Services {
+	t.Helper()
+	st, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewServices(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// fakeTool puts a shell script called name first on the PATH for the
+// rest of the test.
+func fakeTool(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("tool stand-ins are shell scripts")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
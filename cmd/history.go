@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"time"
+)
+
+const historyPageSize = 10
+
+// RecordHistory adds a finished or skipped track to the guild's history.
+func (s *Services) RecordHistory(ev audio.TrackEnded) {
+	switch ev.Reason {
+	case audio.EndFinished, audio.EndSkipped, audio.EndStopped:
+	default:
+		return
+	}
+
+	track := *ev.Track
+	track.Offset = 0
+	s.History.Get(ev.GuildID).Add(audio.HistoryEntry{
+		Track:       &track,
+		RequesterID: track.RequesterID,
+		PlayedAt:    time.Now(),
+		Skipped:     ev.Reason != audio.EndFinished,
+	})
+}
+
+// ShowHistory lists recently played tracks, newest first.
+func (cmd *BotCommand) ShowHistory(page int) {
+	history := cmd.History.Get(cmd.Message.GuildID)
+
+	total := history.Len()
+	if total == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "📭 Nothing has been played yet.")
+		return
+	}
+
+	pages := (total + historyPageSize - 1) / historyPageSize
+	if page < 1 || page > pages {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⚠️ Page must be between 1 and %d.", pages))
+		return
+	}
+
+	offset := (page - 1) * historyPageSize
+	msg := fmt.Sprintf("📜 Recently played (page %d/%d):\n", page, pages)
+	for i, entry := range history.Recent(offset, historyPageSize) {
+		msg += fmt.Sprintf("%d. %s", offset+i+1, entry.Track.Title)
+		if entry.RequesterID != "" {
+			msg += fmt.Sprintf(" — <@%s>", entry.RequesterID)
+		}
+		msg += fmt.Sprintf(" • <t:%d:R>", entry.PlayedAt.Unix())
+		if entry.Skipped {
+			msg += " ⏭️"
+		}
+		msg += "\n"
+	}
+
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
+
+// Previous replays the last played track, putting the current one back at
+// the front of the queue.
+func (cmd *BotCommand) Previous() {
+	guildID := cmd.Message.GuildID
+
+	voice, ok := cmd.ensureVoice()
+	if !ok {
+		return
+	}
+
+	entry, ok := cmd.History.Get(guildID).Pop()
+	if !ok {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ There is no previous track.")
+		return
+	}
+
+	cmd.attachPlayer(voice).Interrupt([]*audio.Track{entry.Track}, true)
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⏮️ Going back to: "+entry.Track.Title)
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"math/rand"
+	"musicbot/audio"
+	"musicbot/favorites"
+)
+
+const likesPageSize = 10
+
+// Like adds the playing track to the author's favorites.
+func (cmd *BotCommand) Like() {
+	current := cmd.QueueManager.Get(cmd.Message.GuildID).Current()
+	if current == nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Nothing is playing.")
+		return
+	}
+	if current.Duration == "" {
+		// Still resolving: save it with its metadata all the same.
+		resolved := *current
+		extractMetadata(&resolved)
+		current = &resolved
+	}
+
+	if err := cmd.Favorites.Like(cmd.Message.Author.ID, current); err == favorites.ErrAlreadyLiked {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "💖 You already like "+current.Title+".")
+		return
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "💖 Added "+current.Title+" to your likes.")
+}
+
+// Unlike removes the playing track, or the one at position n of >likes,
+// from the author's favorites.
+func (cmd *BotCommand) Unlike(n int) {
+	var track *audio.Track
+	if n == 0 {
+		track = cmd.QueueManager.Get(cmd.Message.GuildID).Current()
+		if track == nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Nothing is playing. Use `>unlike <number>` with a number from `>likes`.")
+			return
+		}
+	} else {
+		likes := cmd.Favorites.List(cmd.Message.Author.ID)
+		if n < 1 || n > len(likes) {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Invalid number.")
+			return
+		}
+		track = likes[n-1].Track
+	}
+
+	removed, err := cmd.Favorites.Unlike(cmd.Message.Author.ID, track)
+	if err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "💔 "+track.Title+" isn't one of your likes.")
+		return
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "💔 Removed "+removed.Track.Title+" from your likes.")
+}
+
+// ShowLikes lists the author's favorites, most recently liked first.
+func (cmd *BotCommand) ShowLikes(page int) {
+	likes := cmd.Favorites.List(cmd.Message.Author.ID)
+	if len(likes) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "💔 You haven't liked anything yet. Use `>like` while a track plays.")
+		return
+	}
+
+	pages := (len(likes) + likesPageSize - 1) / likesPageSize
+	if page < 1 || page > pages {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⚠️ Page must be between 1 and %d.", pages))
+		return
+	}
+
+	offset := (page - 1) * likesPageSize
+	msg := fmt.Sprintf("💖 Your likes (page %d/%d):\n", page, pages)
+	for i, like := range likes[offset:min(offset+likesPageSize, len(likes))] {
+		msg += fmt.Sprintf("%d. %s", offset+i+1, like.Track.Title)
+		if like.Track.Duration != "" {
+			msg += " `" + like.Track.Duration + "`"
+		}
+		msg += fmt.Sprintf(" • <t:%d:R>\n", like.LikedAt.Unix())
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
+
+// PlayLikes queues all of the author's favorites, oldest first or shuffled.
+func (cmd *BotCommand) PlayLikes(shuffle bool) {
+	likes := cmd.Favorites.List(cmd.Message.Author.ID)
+	if len(likes) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "💔 You haven't liked anything yet. Use `>like` while a track plays.")
+		return
+	}
+
+	tracks := make([]*audio.Track, len(likes))
+	for i, like := range likes {
+		tracks[len(likes)-1-i] = like.Track
+	}
+	if shuffle {
+		rand.Shuffle(len(tracks), func(i, j int) { tracks[i], tracks[j] = tracks[j], tracks[i] })
+	}
+
+	voice, ok := cmd.ensureVoice()
+	if !ok {
+		return
+	}
+	if !cmd.enqueueBatch(tracks, "your likes") {
+		return
+	}
+	cmd.startPlayback(voice)
+}
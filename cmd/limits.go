@@ -0,0 +1,244 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/settings"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// admission checks tracks the author wants to queue against the guild's
+// limits. newAdmission does the slow part, asking Discord whether the
+// author is exempt and yt-dlp for track lengths, so that check can run
+// with the queue locked and nothing else gets queued in between.
+type admission struct {
+	limits       settings.Limits
+	authorID     string
+	fromPlaylist bool
+	// played holds the keys of recently played tracks, which
+	// DuplicatesReject turns away.
+	played map[string]bool
+}
+
+// RECENT_DUPLICATES is how many of the last played tracks
+// DuplicatesReject counts as duplicates.
+const RECENT_DUPLICATES = 20
+
+// newAdmission prepares to admit tracks. DJs and admins are exempt.
+func (cmd *BotCommand) newAdmission(tracks []*audio.Track, fromPlaylist bool) *admission {
+	a := &admission{authorID: cmd.Message.Author.ID, fromPlaylist: fromPlaylist}
+	limits := cmd.Settings.Get(cmd.Message.GuildID).Limits
+	if limits == (settings.Limits{}) || cmd.isDJ() {
+		return a
+	}
+	a.limits = limits
+
+	if limits.Duplicates == settings.DuplicatesReject {
+		a.played = make(map[string]bool)
+		for _, entry := range cmd.History.Get(cmd.Message.GuildID).Recent(0, RECENT_DUPLICATES) {
+			a.played[entry.Track.Key()] = true
+		}
+	}
+
+	// Playlist entries are only checked if yt-dlp listed their length.
+	if limits.MaxTrackLength > 0 && !fromPlaylist {
+		for _, t := range tracks {
+			if t.Duration == "" {
+				extractMetadata(t)
+			}
+		}
+	}
+	return a
+}
+
+// check returns those of tracks that may join the queue e holds and, if
+// any were turned away, why.
+func (a *admission) check(e *audio.QueueEdit, tracks []*audio.Track) ([]*audio.Track, string) {
+	limits := a.limits
+	if limits == (settings.Limits{}) {
+		return tracks, ""
+	}
+	requested := len(tracks)
+
+	var reasons []string
+	refuse := func(reason string) {
+		for _, r := range reasons {
+			if r == reason {
+				return
+			}
+		}
+		reasons = append(reasons, reason)
+	}
+
+	if a.fromPlaylist && limits.MaxPlaylist > 0 && len(tracks) > limits.MaxPlaylist {
+		tracks = tracks[:limits.MaxPlaylist]
+		refuse(fmt.Sprintf("only the first %d tracks of a playlist can be imported", limits.MaxPlaylist))
+	}
+
+	queued := e.Tracks()
+	seen := make(map[string]bool)
+	if current := e.Current(); current != nil {
+		seen[current.Key()] = true
+	}
+	for _, t := range queued {
+		seen[t.Key()] = true
+	}
+
+	mine := 0
+	for _, t := range queued {
+		if t.RequesterID == a.authorID {
+			mine++
+		}
+	}
+
+	var admitted []*audio.Track
+	for _, t := range tracks {
+		if limits.Duplicates != settings.DuplicatesAllow && seen[t.Key()] {
+			refuse("tracks that are already queued or playing can't be added again")
+			continue
+		}
+		if a.played[t.Key()] {
+			refuse(fmt.Sprintf("tracks among the last %d played can't be added again", RECENT_DUPLICATES))
+			continue
+		}
+		if limits.Duplicates == settings.DuplicatesReject {
+			// Nor can the same track be added twice at once.
+			seen[t.Key()] = true
+		}
+
+		if limits.MaxTrackLength > 0 {
+			if length, ok := t.Length(); ok && length > limits.MaxTrackLength {
+				refuse("tracks can be at most " + formatLength(limits.MaxTrackLength) + " long")
+				continue
+			}
+		}
+
+		if limits.MaxQueue > 0 && len(queued)+len(admitted) >= limits.MaxQueue {
+			refuse(fmt.Sprintf("the queue is full (%d tracks)", limits.MaxQueue))
+			break
+		}
+		if limits.MaxPerUser > 0 && mine+len(admitted) >= limits.MaxPerUser {
+			refuse(fmt.Sprintf("you can have at most %d tracks queued", limits.MaxPerUser))
+			break
+		}
+		admitted = append(admitted, t)
+	}
+
+	if len(reasons) == 0 {
+		return admitted, ""
+	}
+	msg := "🚫 Couldn't add that: "
+	if len(admitted) > 0 {
+		msg = fmt.Sprintf("🚫 %d of %d tracks weren't added: ", requested-len(admitted), requested)
+	}
+	return admitted, msg + strings.Join(reasons, "; ") + "."
+}
+
+// Limit shows or changes the guild's queue limits.
+func (cmd *BotCommand) Limit(args []string) {
+	guildID := cmd.Message.GuildID
+	usage := "Usage: `>limit queue|peruser|playlist <count|off>`, `>limit duration <length|off>` or `>limit duplicates allow|reject|reject-queued`"
+
+	if len(args) == 0 {
+		l := cmd.Settings.Get(guildID).Limits
+		duplicates := "allow"
+		switch l.Duplicates {
+		case settings.DuplicatesReject:
+			duplicates = fmt.Sprintf("reject (also the last %d played)", RECENT_DUPLICATES)
+		case settings.DuplicatesRejectQueued:
+			duplicates = "reject-queued (only what's queued or playing)"
+		}
+		length := "off"
+		if l.MaxTrackLength > 0 {
+			length = formatLength(l.MaxTrackLength)
+		}
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf(
+			"📏 Limits (DJs and admins are exempt):\n• Queue length: %s\n• Tracks per user: %s\n• Track length: %s\n• Playlist import: %s\n• Duplicates: %s",
+			limitText(l.MaxQueue), limitText(l.MaxPerUser), length, limitText(l.MaxPlaylist), duplicates))
+		return
+	}
+
+	if !cmd.isAdmin() {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ Only server admins can change limits.")
+		return
+	}
+	if len(args) != 2 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, usage)
+		return
+	}
+
+	var update func(l *settings.Limits)
+	switch args[0] {
+	case "queue", "peruser", "playlist":
+		n := 0
+		if args[1] != "off" {
+			var err error
+			n, err = strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Use a positive number or `off`.")
+				return
+			}
+		}
+		switch args[0] {
+		case "queue":
+			update = func(l *settings.Limits) { l.MaxQueue = n }
+		case "peruser":
+			update = func(l *settings.Limits) { l.MaxPerUser = n }
+		case "playlist":
+			update = func(l *settings.Limits) { l.MaxPlaylist = n }
+		}
+
+	case "duration":
+		var length time.Duration
+		if args[1] != "off" {
+			var ok bool
+			length, ok = parseLength(args[1])
+			if !ok || length <= 0 {
+				cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Use a length like `10:00`, `1:30:00` or `45m`, or `off`.")
+				return
+			}
+		}
+		update = func(l *settings.Limits) { l.MaxTrackLength = length }
+
+	case "duplicates":
+		var policy settings.DuplicatePolicy
+		switch args[1] {
+		case "allow":
+			policy = settings.DuplicatesAllow
+		case "reject":
+			policy = settings.DuplicatesReject
+		case "reject-queued":
+			policy = settings.DuplicatesRejectQueued
+		default:
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⚠️ Use `allow`, `reject` (also turns away the last %d tracks played) or `reject-queued` (only turns away what's queued or playing).", RECENT_DUPLICATES))
+			return
+		}
+		update = func(l *settings.Limits) { l.Duplicates = policy }
+
+	default:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, usage)
+		return
+	}
+
+	cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { update(&gs.Limits) })
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "📏 Updated the "+args[0]+" limit.")
+}
+
+func limitText(n int) string {
+	if n == 0 {
+		return "off"
+	}
+	return strconv.Itoa(n)
+}
+
+// parseLength accepts yt-dlp style lengths ("4:05", "1:02:03") as well as
+// Go durations ("45m", "1h30m").
+func parseLength(s string) (time.Duration, bool) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, true
+	}
+	t := audio.Track{Duration: s}
+	return t.Length()
+}
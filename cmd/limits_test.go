@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/settings"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func tracksOf(requesterID string, urls ...string) []*audio.Track {
+	var tracks []*audio.Track
+	for _, url := range urls {
+		tracks = append(tracks, &audio.Track{URL: url, RequesterID: requesterID})
+	}
+	return tracks
+}
+
+func urlsOf(tracks []*audio.Track) string {
+	var urls []string
+	for _, t := range tracks {
+		urls = append(urls, t.URL)
+	}
+	return strings.Join(urls, " ")
+}
+
+// admit checks tracks from alice against a queue holding queued, with
+// playing as the current track.
+func admit(limits settings.Limits, playing string, queued []string, requested ...string) ([]*audio.Track, string) {
+	return admitAs(&admission{limits: limits, authorID: "alice"}, playing, queued, requested...)
+}
+
+func admitAs(a *admission, playing string, queued []string, requested ...string) ([]*audio.Track, string) {
+	queue := audio.NewQueueManager(nil).Get("guild")
+	queue.EnqueueMultiple(tracksOf("bob", append([]string{playing}, queued...)...))
+	queue.Dequeue()
+
+	var admitted []*audio.Track
+	var refused string
+	queue.Edit(func(e *audio.QueueEdit) {
+		admitted, refused = a.check(e, tracksOf("alice", requested...))
+	})
+	return admitted, refused
+}
+
+func TestDuplicatePolicies(t *testing.T) {
+	for _, tt := range []struct {
+		policy settings.DuplicatePolicy
+		want   string
+	}{
+		{settings.DuplicatesAllow, "a b c c d"},
+		{settings.DuplicatesRejectQueued, "c c d"},
+		{settings.DuplicatesReject, "c"},
+	} {
+		// d was played a moment ago; only DuplicatesReject looks that far.
+		a := &admission{limits: settings.Limits{Duplicates: tt.policy}, authorID: "alice"}
+		if tt.policy == settings.DuplicatesReject {
+			a.played = map[string]bool{"youtube:d": true}
+		}
+		admitted, refused := admitAs(a, "https://youtu.be/b", []string{"https://youtu.be/a"},
+			"https://www.youtube.com/watch?v=a", "https://youtu.be/b", "https://youtu.be/c", "https://youtu.be/c", "https://youtu.be/d")
+
+		var got []string
+		for _, t := range admitted {
+			got = append(got, strings.TrimPrefix(t.Key(), "youtube:"))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%q admitted %v, want %s", tt.policy, got, tt.want)
+		}
+		if (tt.policy == settings.DuplicatesAllow) != (refused == "") {
+			t.Errorf("%q refused with %q", tt.policy, refused)
+		}
+	}
+}
+
+func TestQueueLimits(t *testing.T) {
+	queued := []string{"q1", "q2", "q3"}
+
+	admitted, refused := admit(settings.Limits{MaxQueue: 4}, "now", queued, "a", "b")
+	if urlsOf(admitted) != "a" || !strings.Contains(refused, "the queue is full (4 tracks)") {
+		t.Errorf("max queue admitted %q, refused with %q", urlsOf(admitted), refused)
+	}
+
+	admitted, refused = admit(settings.Limits{MaxPerUser: 2}, "now", queued, "a", "b", "c")
+	if urlsOf(admitted) != "a b" || !strings.Contains(refused, "1 of 3 tracks weren't added") {
+		t.Errorf("max per user admitted %q, refused with %q", urlsOf(admitted), refused)
+	}
+}
+
+// TestAdmissionIsAtomic queues from many goroutines at once, as concurrent
+// >play commands would. None may get past the queue limit.
+func TestAdmissionIsAtomic(t *testing.T) {
+	const limit = 5
+	queue := audio.NewQueueManager(nil).Get("guild")
+	a := &admission{limits: settings.Limits{MaxQueue: limit}, authorID: "alice"}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			queue.Edit(func(e *audio.QueueEdit) {
+				tracks, _ := a.check(e, tracksOf("alice", fmt.Sprint(i)))
+				e.EnqueueMultiple(tracks)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if n := len(queue.List()); n != limit {
+		t.Errorf("queued %d tracks, want the limit of %d", n, limit)
+	}
+}
@@ -0,0 +1,30 @@
+package commands
+
+import "github.com/bwmarrin/discordgo"
+
+// isAdmin reports whether the message author can manage the guild.
+func (cmd *BotCommand) isAdmin() bool {
+	perms, err := cmd.Session.UserChannelPermissions(cmd.Message.Author.ID, cmd.Message.ChannelID)
+	if err != nil {
+		return false
+	}
+	return perms&(discordgo.PermissionAdministrator|discordgo.PermissionManageGuild) != 0
+}
+
+// isDJ reports whether the message author has the guild's DJ role or is
+// an admin.
+func (cmd *BotCommand) isDJ() bool {
+	if cmd.isAdmin() {
+		return true
+	}
+	roleID := cmd.Settings.Get(cmd.Message.GuildID).DJRoleID
+	if roleID == "" || cmd.Message.Member == nil {
+		return false
+	}
+	for _, id := range cmd.Message.Member.Roles {
+		if id == roleID {
+			return true
+		}
+	}
+	return false
+}
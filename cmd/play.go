@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"musicbot/audio"
+	"musicbot/discord"
 	"musicbot/vc"
 	"os/exec"
 	"strconv"
@@ -13,25 +14,21 @@ import (
 )
 
 type BotCommand struct {
-	Session       *discordgo.Session
-	Message       *discordgo.MessageCreate
-	VoiceManager  *vc.VoiceManager
-	QueueManager  *audio.QueueManager
-	AudioSessions *audio.AudioSessionManager // ✅ Thread-safe session manager
+	Session discord.Client
+	Message *discordgo.MessageCreate
+	*Services
 }
 
-func NewBotCommand(s *discordgo.Session, m *discordgo.MessageCreate, vc *vc.VoiceManager, queue *audio.QueueManager, sessions *audio.AudioSessionManager) *BotCommand {
+func NewBotCommand(s discord.Client, m *discordgo.MessageCreate, services *Services) *BotCommand {
 	return &BotCommand{
-		Session:       s,
-		Message:       m,
-		VoiceManager:  vc,
-		QueueManager:  queue,
-		AudioSessions: sessions,
+		Session:  s,
+		Message:  m,
+		Services: services,
 	}
 }
 
 func (cmd *BotCommand) getUserVoiceChannelID() string {
-	guild, _ := cmd.Session.State.Guild(cmd.Message.GuildID)
+	guild, _ := cmd.Session.StateGuild(cmd.Message.GuildID)
 	if guild == nil {
 		return ""
 	}
@@ -64,15 +61,12 @@ func (cmd *BotCommand) Join() {
 func (cmd *BotCommand) Leave() {
 	guildID := cmd.Message.GuildID
 
-	if state, ok := cmd.AudioSessions.Get(guildID); ok {
-		state.Conn.Stop()
-		cmd.AudioSessions.Delete(guildID)
-	}
-
-	queue := cmd.QueueManager.Get(guildID)
-	queue.Clear()
-	queue.CurrentTrack = nil
-	queue.IsPlaying = false
+	cmd.journaledStop("leave")
+	cmd.CancelExpansions(guildID)
+	player := cmd.Players.Get(guildID)
+	player.Stop()
+	player.Detach()
+	cmd.Encoders.Release(guildID)
 
 	err := cmd.VoiceManager.Leave(guildID)
 	if err != nil {
@@ -83,156 +77,226 @@ func (cmd *BotCommand) Leave() {
 	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "👋 Disconnected from voice channel.")
 }
 
-func (cmd *BotCommand) Play(input string) {
+// ensureVoice returns the guild's voice connection, joining the author's
+// channel first if needed. It reports false after telling the author why
+// it could not.
+func (cmd *BotCommand) ensureVoice() (*vc.Voice, bool) {
 	guildID := cmd.Message.GuildID
 	userChannelID := cmd.getUserVoiceChannelID()
 
 	if userChannelID == "" {
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔊 You must be in a voice channel.")
-		return
+		return nil, false
 	}
 
-	vc, ok := cmd.VoiceManager.Get(guildID)
+	voice, ok := cmd.VoiceManager.Get(guildID)
 	if !ok {
 		cmd.Join()
-		vc, ok = cmd.VoiceManager.Get(guildID)
+		voice, ok = cmd.VoiceManager.Get(guildID)
 		if !ok {
 			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ Failed to join your voice channel.")
-			return
+			return nil, false
 		}
 	}
+	return voice, true
+}
 
-	queue := cmd.QueueManager.Get(guildID)
-
-	// Handle playlist
-	if strings.Contains(input, "playlist?") {
-		tracks, err := audio.ExtractPlaylistTracks(input)
-		if err != nil || len(tracks) == 0 {
-			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Failed to extract playlist.")
-			return
-		}
-		queue.EnqueueMultiple(tracks)
-		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("📜 Enqueued %d tracks from playlist.", len(tracks)))
+// Play queues a track, or the entries of a playlist picked by options
+// ("shuffle" and a range like "10-20").
+func (cmd *BotCommand) Play(input string, options []string) {
+	isPlaylist := audio.IsPlaylistURL(input)
+	opts, ok := parsePlaylistOptions(options)
+	if !ok || len(options) > 0 && !isPlaylist {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "Usage: `>play <url>` or `>play <playlist url> [shuffle] [from-to]`")
+		return
+	}
 
-		// Extract metadata for each track in background
-		for _, t := range tracks {
-			go extractMetadata(t)
-		}
-	} else {
-		track := &audio.Track{
-			URL:      input,
-			Title:    input,
-			Duration: "",
-			Uploader: "",
-		}
-		queue.Enqueue(track)
-		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🎶 Added to queue.")
-		go extractMetadata(track)
+	vc, ok := cmd.ensureVoice()
+	if !ok {
+		return
 	}
 
-	if _, exists := cmd.AudioSessions.Get(guildID); !exists {
-		cmd.AudioSessions.Set(guildID, &audio.GuildAudioState{Conn: audio.NewConnection(vc)})
+	// Handle playlist
+	if isPlaylist {
+		cmd.expandPlaylist(vc, input, opts)
+		return
 	}
 
-	if queue.IsPlaying {
+	track := &audio.Track{
+		URL:      input,
+		Title:    input,
+		Duration: "",
+		Uploader: "",
+	}
+	if _, refused := cmd.enqueue([]*audio.Track{track}, false); refused != "" {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, refused)
 		return
 	}
+	// When it starts depends only on the tracks ahead of it, so the reply
+	// needn't wait for its metadata.
+	eta := cmd.playsIn(track)
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🎶 Added to queue."+eta)
+
+	cmd.startPlayback(vc)
+	if track.Duration == "" && eta != "" {
+		go cmd.resolveQueued(cmd.Message.GuildID, track)
+	}
+}
 
-	go cmd.startQueuePlayback(guildID, vc, queue)
+// enqueue adds tracks to the end of the queue on behalf of the author,
+// within the guild's limits. It returns the tracks it queued and, if any
+// were turned away, a message saying why. Every command that queues music
+// goes through here.
+func (cmd *BotCommand) enqueue(tracks []*audio.Track, fromPlaylist bool) ([]*audio.Track, string) {
+	return cmd.enqueueAs("enqueue", tracks, fromPlaylist)
 }
 
-func (cmd *BotCommand) startQueuePlayback(guildID string, vc *discordgo.VoiceConnection, queue *audio.Queue) {
-	queue.IsPlaying = true
-	defer func() {
-		queue.IsPlaying = false
-		queue.CurrentTrack = nil
-		_ = cmd.VoiceManager.Leave(guildID)
-		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "👋 Finished playback. Left the voice channel.")
-	}()
+// enqueueAs is enqueue with the change journaled as action, or not
+// journaled at all if action is "".
+func (cmd *BotCommand) enqueueAs(action string, tracks []*audio.Track, fromPlaylist bool) ([]*audio.Track, string) {
+	admission := cmd.newAdmission(tracks, fromPlaylist)
+	for _, t := range tracks {
+		cmd.attribute(t)
+	}
+	var refused string
+	add := func(e *audio.QueueEdit) {
+		tracks, refused = admission.check(e, tracks)
+		e.EnqueueMultiple(tracks)
+	}
+	if action == "" {
+		cmd.QueueManager.Get(cmd.Message.GuildID).Edit(add)
+	} else {
+		cmd.journaled(action, add)
+	}
+	if len(tracks) > 0 {
+		cmd.Stats.RecordRequests(cmd.Message.GuildID, cmd.Message.Author.ID, tracks)
+	}
+	return tracks, refused
+}
 
-	for {
-		track := queue.Dequeue()
-		if track == nil {
-			break
-		}
+// enqueueBatch queues tracks from a playlist or file and tells the author
+// how many made it in. It reports false if none did.
+func (cmd *BotCommand) enqueueBatch(tracks []*audio.Track, source string) bool {
+	added, refused := cmd.enqueue(tracks, true)
+	if len(added) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, refused)
+		return false
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("📜 Enqueued %d tracks from %s.", len(added), source)+cmd.playsIn(added[0]))
+	if refused != "" {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, refused)
+	}
+	return true
+}
 
-		queue.CurrentTrack = track
-		go cmd.NowPlaying()
+// attribute marks the author as the track's requester.
+func (cmd *BotCommand) attribute(t *audio.Track) {
+	t.RequesterID = cmd.Message.Author.ID
+	t.RequesterName = cmd.authorName()
+	t.EnqueuedAt = time.Now()
+}
 
-		state := &audio.GuildAudioState{Conn: audio.NewConnection(vc)}
-		cmd.AudioSessions.Set(guildID, state)
+// authorName is the author's server nickname, falling back to their
+// display name and then their username.
+func (cmd *BotCommand) authorName() string {
+	if cmd.Message.Member != nil && cmd.Message.Member.Nick != "" {
+		return cmd.Message.Member.Nick
+	}
+	if cmd.Message.Author.GlobalName != "" {
+		return cmd.Message.Author.GlobalName
+	}
+	return cmd.Message.Author.Username
+}
 
-		err := state.Conn.Play(track.URL, &state.Paused, &state.Mutex)
-		if err != nil {
-			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Error playing track: "+err.Error())
-			continue
-		}
+// requesterName is who queued t, or "" if nobody did.
+func requesterName(t *audio.Track) string {
+	if t.RequesterName == "" && t.RequesterID != "" {
+		return "<@" + t.RequesterID + ">"
 	}
+	return t.RequesterName
 }
 
-func (cmd *BotCommand) Stop() {
-	guildID := cmd.Message.GuildID
-	queue := cmd.QueueManager.Get(guildID)
+// requester describes who queued t and when, or "" if nobody did.
+func requester(t *audio.Track) string {
+	name := requesterName(t)
+	if name == "" {
+		return ""
+	}
+	if t.EnqueuedAt.IsZero() {
+		return name
+	}
+	return fmt.Sprintf("%s <t:%d:R>", name, t.EnqueuedAt.Unix())
+}
 
-	queue.Clear()
-	queue.CurrentTrack = nil
-	queue.IsPlaying = false
+// startPlayback points the guild's player at the voice connection and
+// starts it if it is idle.
+func (cmd *BotCommand) startPlayback(voice *vc.Voice) {
+	cmd.attachPlayer(voice).Start()
+}
+
+// attachPlayer points the guild's player and its announcements at the
+// voice connection and the command's channel.
+func (cmd *BotCommand) attachPlayer(voice *vc.Voice) *audio.Player {
+	player := cmd.Players.Get(cmd.Message.GuildID)
+	player.SetTextChannel(cmd.Message.ChannelID)
+	player.Attach(voice.Sink, func() (*audio.Encoder, error) {
+		return cmd.guildEncoder(voice.ChannelID)
+	})
+	return player
+}
 
-	if state, ok := cmd.AudioSessions.Get(guildID); ok {
-		state.Conn.Stop()
-		cmd.AudioSessions.Delete(guildID)
+// ResolveTrack fills in a track's title, duration and uploader with yt-dlp.
+// It is the players' Resolver and runs while a track is in the Resolving state.
+func ResolveTrack(t audio.Track) audio.Track {
+	if t.Duration == "" {
+		extractMetadata(&t)
 	}
+	return t
+}
+
+func (cmd *BotCommand) Stop() {
+	guildID := cmd.Message.GuildID
+	cmd.journaledStop("stop")
+	cmd.CancelExpansions(guildID)
+	cmd.Players.Get(guildID).Stop()
 
 	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⏹️ Stopped playback and cleared the queue.")
 }
 
+// Skip skips the current track, or votes to when the guild has vote skip
+// on. DJs and whoever queued the track skip straight away.
 func (cmd *BotCommand) Skip() {
 	guildID := cmd.Message.GuildID
-	queue := cmd.QueueManager.Get(guildID)
-	state, ok := cmd.AudioSessions.Get(guildID)
-	if !ok || state.Conn == nil {
+	current := cmd.QueueManager.Get(guildID).Current()
+	if current == nil {
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ Nothing is currently playing.")
 		return
 	}
-
-	queue.CurrentTrack = nil
-	state.Conn.Stop()
-
-	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⏭️ Skipped current track.")
-}
-
-func (cmd *BotCommand) Queue() {
-	guildID := cmd.Message.GuildID
-	queue := cmd.QueueManager.Get(guildID)
-	tracks := queue.List()
-
-	msg := ""
-
-	if queue.CurrentTrack != nil {
-		msg += fmt.Sprintf("🎶 Now Playing: %s\n", queue.CurrentTrack.URL)
-	} else {
-		msg += "📭 Nothing is currently playing.\n"
+	if cmd.Settings.Get(guildID).VoteSkip && current.RequesterID != cmd.Message.Author.ID && !cmd.isDJ() {
+		cmd.voteSkip(current)
+		return
 	}
 
-	if len(tracks) == 0 {
-		msg += "🕳️ The queue is empty."
-	} else {
-		msg += "🎼 Upcoming Queue:\n"
-		for i, t := range tracks {
-			msg += fmt.Sprintf("%d. %s\n", i+1, t.URL)
-		}
+	if err := cmd.Players.Get(guildID).SkipTrack(current); err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, skipError(err))
+		return
 	}
 
-	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⏭️ Skipped current track.")
 }
 
 func (cmd *BotCommand) NowPlaying() {
 	guildID := cmd.Message.GuildID
 	queue := cmd.QueueManager.Get(guildID)
 
-	if queue.CurrentTrack != nil {
-		track := queue.CurrentTrack
+	if track := queue.Current(); track != nil {
 		msg := fmt.Sprintf("🎶 Now Playing: %s\n⏱️ Duration: %s\n👤 Uploader: %s", track.Title, track.Duration, track.Uploader)
+		if by := requester(track); by != "" {
+			msg += "\n🙋 Requested by: " + by
+		}
+		if track.Autoplay {
+			msg += "\n📻 Autoplayed"
+		}
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
 		return
 	}
@@ -242,32 +306,24 @@ func (cmd *BotCommand) NowPlaying() {
 
 func (cmd *BotCommand) Pause() {
 	guildID := cmd.Message.GuildID
-	if state, ok := cmd.AudioSessions.Get(guildID); ok {
-		state.Mutex.Lock()
-		defer state.Mutex.Unlock()
-		if state.Paused {
-			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⏸️ Already paused.")
-			return
-		}
-		state.Paused = true
+	switch cmd.Players.Get(guildID).Pause() {
+	case nil:
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⏸️ Paused playback.")
-	} else {
+	case audio.ErrAlreadyPaused:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⏸️ Already paused.")
+	default:
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ Nothing is playing.")
 	}
 }
 
 func (cmd *BotCommand) Resume() {
 	guildID := cmd.Message.GuildID
-	if state, ok := cmd.AudioSessions.Get(guildID); ok {
-		state.Mutex.Lock()
-		defer state.Mutex.Unlock()
-		if !state.Paused {
-			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "▶️ Already playing.")
-			return
-		}
-		state.Paused = false
+	switch cmd.Players.Get(guildID).Resume() {
+	case nil:
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "▶️ Resumed playback.")
-	} else {
+	case audio.ErrNotPaused:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "▶️ Already playing.")
+	default:
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ Nothing is playing.")
 	}
 }
@@ -299,23 +355,38 @@ func (cmd *BotCommand) ToggleLoopMode() {
 }
 
 func (cmd *BotCommand) ClearQueue() {
-	guildID := cmd.Message.GuildID
-	queue := cmd.QueueManager.Get(guildID)
-	queue.Clear()
+	cmd.journaled("clear", (*audio.QueueEdit).Clear)
 	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🧹 Cleared the queue.")
 }
 
-func (cmd *BotCommand) ShuffleQueue() {
+// ShuffleQueue shuffles the queue once, plainly or with "smart" spacing,
+// or turns shuffle mode "on" or "off".
+func (cmd *BotCommand) ShuffleQueue(mode string) {
 	guildID := cmd.Message.GuildID
 	queue := cmd.QueueManager.Get(guildID)
-	queue.Shuffle()
-	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔀 Queue shuffled.")
+
+	switch mode {
+	case "":
+		cmd.journaled("shuffle", (*audio.QueueEdit).Shuffle)
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔀 Queue shuffled.")
+	case "smart":
+		cmd.journaled("smart shuffle", (*audio.QueueEdit).SmartShuffle)
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔀 Queue shuffled, keeping the same uploaders and requesters apart.")
+	case "on", "off":
+		queue.SetShuffle(mode == "on")
+		if mode == "on" && queue.Fair() {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔀 Shuffle mode is on, but fair queue decides where new tracks go while it's on.")
+			return
+		}
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔀 Shuffle mode is now "+mode+".")
+	default:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "Usage: `>queue shuffle [smart|on|off]`")
+	}
 }
 
 func (cmd *BotCommand) RemoveFromQueue(index int) {
-	guildID := cmd.Message.GuildID
-	queue := cmd.QueueManager.Get(guildID)
-	ok := queue.Remove(index - 1)
+	var ok bool
+	cmd.journaled("remove", func(e *audio.QueueEdit) { ok = e.Remove(index - 1) })
 	if ok {
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("❌ Removed track %d from queue.", index))
 	} else {
@@ -326,6 +397,9 @@ func (cmd *BotCommand) RemoveFromQueue(index int) {
 func (cmd *BotCommand) InsertIntoQueue(index int, url string) {
 	guildID := cmd.Message.GuildID
 	queue := cmd.QueueManager.Get(guildID)
+	if !cmd.canReorder(queue) {
+		return
+	}
 
 	// Fetch metadata
 	cmdYTDLP := exec.Command("yt-dlp", "--print", "%(title)s|%(duration_string)s|%(uploader)s", url)
@@ -345,18 +419,46 @@ func (cmd *BotCommand) InsertIntoQueue(index int, url string) {
 		Duration: duration,
 		Uploader: uploader,
 	}
-	ok := queue.Insert(index-1, track)
+	admission := cmd.newAdmission([]*audio.Track{track}, false)
+	cmd.attribute(track)
+	var refused string
+	var ok bool
+	cmd.journaled("insert", func(e *audio.QueueEdit) {
+		var admitted []*audio.Track
+		if admitted, refused = admission.check(e, []*audio.Track{track}); len(admitted) > 0 {
+			ok = e.Insert(index-1, track)
+		}
+	})
+	if refused != "" {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, refused)
+		return
+	}
 	if ok {
+		cmd.Stats.RecordRequests(guildID, cmd.Message.Author.ID, []*audio.Track{track})
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("➕ Inserted at position %d: %s", index, title))
 	} else {
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Invalid insert position.")
 	}
 }
 
+// canReorder reports whether the author may place tracks by hand. In fair
+// mode that would jump the round-robin order, so only DJs can.
+func (cmd *BotCommand) canReorder(queue *audio.Queue) bool {
+	if !queue.Fair() || cmd.isDJ() {
+		return true
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ Fair queue is on, so only DJs can reorder the queue.")
+	return false
+}
+
 func (cmd *BotCommand) MoveInQueue(from, to int) {
 	guildID := cmd.Message.GuildID
 	queue := cmd.QueueManager.Get(guildID)
-	success := queue.Move(from-1, to-1)
+	if !cmd.canReorder(queue) {
+		return
+	}
+	var success bool
+	cmd.journaled("move", func(e *audio.QueueEdit) { success = e.Move(from-1, to-1) })
 	if success {
 		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID,
 			fmt.Sprintf("🔁 Moved track from position %d to %d.", from, to))
@@ -420,7 +522,7 @@ func waitForSelection(cmd *BotCommand, results []SearchResult) {
 	channelID := cmd.Message.ChannelID
 
 	var remove func()
-	handler := func(s *discordgo.Session, m *discordgo.MessageCreate) {
+	handler := func(_ *discordgo.Session, m *discordgo.MessageCreate) {
 		if m.Author.ID != userID || m.ChannelID != channelID {
 			return
 		}
@@ -436,7 +538,7 @@ func waitForSelection(cmd *BotCommand, results []SearchResult) {
 
 		// Now safely remove the handler
 		remove()
-		cmd.Play(selected.URL)
+		cmd.Play(selected.URL, nil)
 	}
 
 	// Set the remove function AFTER handler is defined
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+)
+
+// playlistShowLimit caps how many tracks >playlist show lists.
+const playlistShowLimit = 20
+
+// PlaylistSave saves the current track and queue as a playlist.
+func (cmd *BotCommand) PlaylistSave(name string) {
+	// The snapshot leaves out the copy of the current track that loop-all
+	// has re-queued at the end.
+	snap := cmd.QueueManager.Get(cmd.Message.GuildID).Snapshot(0)
+
+	var tracks []*audio.Track
+	if snap.Current != nil {
+		tracks = append(tracks, snap.Current)
+	}
+	tracks = append(tracks, snap.Tracks...)
+	if len(tracks) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🕳️ The queue is empty, nothing to save.")
+		return
+	}
+
+	p := cmd.Playlists.Save(cmd.Message.Author.ID, cmd.Message.GuildID, name, tracks)
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("💾 Saved %d tracks to playlist **%s**.", len(p.Tracks), p.Name))
+}
+
+// PlaylistAdd resolves url and appends it to one of the author's playlists.
+func (cmd *BotCommand) PlaylistAdd(name, url string) {
+	track := &audio.Track{URL: url, Title: url}
+	extractMetadata(track)
+
+	p := cmd.Playlists.Add(cmd.Message.Author.ID, cmd.Message.GuildID, name, track)
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("➕ Added %s to **%s** (%d tracks).", track.Title, p.Name, len(p.Tracks)))
+}
+
+// PlaylistLoad enqueues a playlist's tracks using their saved metadata.
+func (cmd *BotCommand) PlaylistLoad(name string) {
+	p, err := cmd.Playlists.Find(cmd.Message.Author.ID, cmd.Message.GuildID, name)
+	if err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ No playlist called **"+name+"**.")
+		return
+	}
+	if len(p.Tracks) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🕳️ Playlist **"+p.Name+"** is empty.")
+		return
+	}
+
+	voice, ok := cmd.ensureVoice()
+	if !ok {
+		return
+	}
+
+	if !cmd.enqueueBatch(p.Tracks, "playlist **"+p.Name+"**") {
+		return
+	}
+	cmd.startPlayback(voice)
+}
+
+// PlaylistShow lists the tracks of a playlist.
+func (cmd *BotCommand) PlaylistShow(name string) {
+	p, err := cmd.Playlists.Find(cmd.Message.Author.ID, cmd.Message.GuildID, name)
+	if err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ No playlist called **"+name+"**.")
+		return
+	}
+
+	msg := fmt.Sprintf("📜 **%s** by <@%s> (%d tracks)", p.Name, p.OwnerID, len(p.Tracks))
+	if p.Shared {
+		msg += " • shared"
+	}
+	msg += "\n"
+	for i, t := range p.Tracks {
+		if i == playlistShowLimit {
+			msg += fmt.Sprintf("…and %d more\n", len(p.Tracks)-playlistShowLimit)
+			break
+		}
+		msg += fmt.Sprintf("%d. %s", i+1, t.Title)
+		if t.Duration != "" {
+			msg += " (" + t.Duration + ")"
+		}
+		msg += "\n"
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
+
+func (cmd *BotCommand) PlaylistDelete(name string) {
+	if err := cmd.Playlists.Delete(cmd.Message.Author.ID, name); err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ You have no playlist called **"+name+"**.")
+		return
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🗑️ Deleted playlist **"+name+"**.")
+}
+
+// PlaylistShare shares or unshares one of the author's playlists with the guild.
+func (cmd *BotCommand) PlaylistShare(name, state string) {
+	shared, ok := parseOnOff(state)
+	if !ok {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Use `on` or `off`.")
+		return
+	}
+	if err := cmd.Playlists.SetShared(cmd.Message.Author.ID, name, shared); err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ You have no playlist called **"+name+"**.")
+		return
+	}
+	if shared {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🤝 Playlist **"+name+"** is now shared with this server.")
+	} else {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔒 Playlist **"+name+"** is now private.")
+	}
+}
+
+// PlaylistList lists the author's playlists and those shared with the guild.
+func (cmd *BotCommand) PlaylistList() {
+	playlists := cmd.Playlists.List(cmd.Message.Author.ID, cmd.Message.GuildID)
+	if len(playlists) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "📭 No playlists yet. Create one with `>playlist save <name>`.")
+		return
+	}
+
+	msg := "📚 Playlists:\n"
+	for _, p := range playlists {
+		msg += fmt.Sprintf("• **%s** — %d tracks", p.Name, len(p.Tracks))
+		if p.OwnerID != cmd.Message.Author.ID {
+			msg += fmt.Sprintf(" (shared by <@%s>)", p.OwnerID)
+		} else if p.Shared {
+			msg += " (shared)"
+		}
+		msg += "\n"
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"os/exec"
+	"strings"
+)
+
+// resolveInput turns a URL, playlist URL or search query into tracks with
+// their metadata filled in. It reports whether they came from a playlist.
+func (cmd *BotCommand) resolveInput(input string) ([]*audio.Track, bool, error) {
+	if audio.IsPlaylistURL(input) {
+		tracks, err := audio.ExtractPlaylistTracks(input, audio.PlaylistOptions{Limit: cmd.playlistLimit()})
+		if err == nil && len(tracks) == 0 {
+			err = fmt.Errorf("the playlist is empty")
+		}
+		return tracks, true, err
+	}
+
+	if strings.Contains(input, "://") {
+		track := &audio.Track{URL: input, Title: input}
+		extractMetadata(track)
+		return []*audio.Track{track}, false, nil
+	}
+
+	searchCmd := exec.Command("yt-dlp", "ytsearch1:"+input, "--print", "%(webpage_url)s|%(duration_string)s|%(uploader)s|%(title)s")
+	output, err := searchCmd.Output()
+	if err != nil {
+		return nil, false, err
+	}
+	parts := strings.SplitN(strings.TrimSpace(string(output)), "|", 4)
+	if len(parts) != 4 {
+		return nil, false, fmt.Errorf("no results for %q", input)
+	}
+	return []*audio.Track{{URL: parts[0], Duration: parts[1], Uploader: parts[2], Title: parts[3]}}, false, nil
+}
+
+// prepareUrgent resolves input into tracks to jump the queue with, after
+// checking the author may, and prepares to check them against the guild's
+// limits. It reports false after telling the author why not.
+func (cmd *BotCommand) prepareUrgent(input string) ([]*audio.Track, *admission, bool) {
+	if !cmd.canReorder(cmd.QueueManager.Get(cmd.Message.GuildID)) {
+		return nil, nil, false
+	}
+
+	tracks, fromPlaylist, err := cmd.resolveInput(input)
+	if err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Couldn't find that: "+err.Error())
+		return nil, nil, false
+	}
+
+	admission := cmd.newAdmission(tracks, fromPlaylist)
+	for _, t := range tracks {
+		cmd.attribute(t)
+	}
+	return tracks, admission, true
+}
+
+// admitted tells the author why any of their tracks were refused, and
+// counts those that were queued as their requests. It reports false if
+// none were.
+func (cmd *BotCommand) admitted(tracks []*audio.Track, refused string) bool {
+	if refused != "" {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, refused)
+	}
+	if len(tracks) == 0 {
+		return false
+	}
+	cmd.Stats.RecordRequests(cmd.Message.GuildID, cmd.Message.Author.ID, tracks)
+	return true
+}
+
+func describeTracks(tracks []*audio.Track) string {
+	if len(tracks) == 1 {
+		return tracks[0].Title
+	}
+	return fmt.Sprintf("%d tracks", len(tracks))
+}
+
+// PlayNext puts a track, or a playlist as one block, at the head of the queue.
+func (cmd *BotCommand) PlayNext(input string) {
+	voice, ok := cmd.ensureVoice()
+	if !ok {
+		return
+	}
+	tracks, admission, ok := cmd.prepareUrgent(input)
+	if !ok {
+		return
+	}
+
+	var refused string
+	cmd.journaled("play next", func(e *audio.QueueEdit) {
+		tracks, refused = admission.check(e, tracks)
+		e.PushFront(tracks...)
+	})
+	if !cmd.admitted(tracks, refused) {
+		return
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⏭️ Playing next: "+describeTracks(tracks))
+	cmd.startPlayback(voice)
+}
+
+// PlayNow plays a track, or a playlist as one block, straight away. With
+// keep, the interrupted track resumes from where it stopped afterwards.
+func (cmd *BotCommand) PlayNow(input string, keep bool) {
+	guildID := cmd.Message.GuildID
+	if cmd.Settings.Get(guildID).VoteSkip && cmd.QueueManager.Get(guildID).Current() != nil && !cmd.isDJ() {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ Vote skip is on, so only DJs can interrupt the current track.")
+		return
+	}
+
+	voice, ok := cmd.ensureVoice()
+	if !ok {
+		return
+	}
+	tracks, admission, ok := cmd.prepareUrgent(input)
+	if !ok {
+		return
+	}
+
+	// The player makes the edit, so it is journaled here, with the queue
+	// locked, once the tracks are known to be going in. Play now takes
+	// nothing out of the queue, so what was queued before is all kept.
+	var refused string
+	cmd.attachPlayer(voice).InterruptWith(tracks, keep, func(e *audio.QueueEdit, requested []*audio.Track) []*audio.Track {
+		tracks, refused = admission.check(e, requested)
+		if len(tracks) > 0 {
+			before := e.Tracks()
+			cmd.record("play now", before, e.LoopMode(), before)
+		}
+		return tracks
+	})
+	if !cmd.admitted(tracks, refused) {
+		return
+	}
+	msg := "▶️ Playing now: " + describeTracks(tracks)
+	if keep {
+		msg += "\n↩️ The interrupted track will resume afterwards."
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
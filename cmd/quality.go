@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/settings"
+	"strconv"
+	"strings"
+)
+
+// encoderSettings merges the guild's >quality overrides over the settings
+// derived from the voice channel's bitrate.
+func (cmd *BotCommand) encoderSettings(channelID string) audio.EncoderSettings {
+	bitrate := 0
+	if channel, err := cmd.Session.StateChannel(channelID); err == nil {
+		bitrate = channel.Bitrate
+	}
+	s := audio.EncoderSettingsForBitrate(bitrate)
+
+	o := cmd.Settings.Get(cmd.Message.GuildID).Quality
+	if o.Bitrate != nil {
+		s.Bitrate = *o.Bitrate
+	}
+	if o.Complexity != nil {
+		s.Complexity = *o.Complexity
+	}
+	if o.FEC != nil {
+		s.FEC = *o.FEC
+	}
+	if o.PacketLoss != nil {
+		s.PacketLoss = *o.PacketLoss
+	}
+	return s
+}
+
+func (cmd *BotCommand) guildEncoder(channelID string) (*audio.Encoder, error) {
+	return cmd.Encoders.Get(cmd.Message.GuildID, cmd.encoderSettings(channelID))
+}
+
+// Quality shows or overrides the guild's Opus encoder settings.
+func (cmd *BotCommand) Quality(args []string) {
+	guildID := cmd.Message.GuildID
+
+	if len(args) == 0 {
+		channelID := ""
+		if vc, ok := cmd.VoiceManager.Get(guildID); ok {
+			channelID = vc.ChannelID
+		} else {
+			channelID = cmd.getUserVoiceChannelID()
+		}
+		s := cmd.encoderSettings(channelID)
+		o := cmd.Settings.Get(guildID).Quality
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf(
+			"🎚️ Audio quality:\n• Bitrate: %d kbps%s\n• Complexity: %d%s\n• FEC: %s%s\n• Expected packet loss: %d%%%s",
+			s.Bitrate/1000, overrideMark(o.Bitrate != nil),
+			s.Complexity, overrideMark(o.Complexity != nil),
+			onOff(s.FEC), overrideMark(o.FEC != nil),
+			s.PacketLoss, overrideMark(o.PacketLoss != nil)))
+		return
+	}
+
+	if !cmd.isAdmin() {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ Only server admins can change audio quality.")
+		return
+	}
+
+	if args[0] == "reset" {
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) {
+			gs.Quality = settings.QualityOverrides{}
+		})
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🎚️ Audio quality reset to channel defaults.")
+		return
+	}
+
+	if len(args) != 2 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "Usage: `>quality bitrate <kbps> | complexity <0-10> | fec on|off | loss <0-100> | reset`")
+		return
+	}
+
+	value := strings.ToLower(args[1])
+	switch args[0] {
+	case "bitrate":
+		kbps, err := strconv.Atoi(value)
+		bitrate := kbps * 1000
+		if err != nil || bitrate < audio.MIN_BITRATE || bitrate > audio.MAX_BITRATE {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⚠️ Bitrate must be between %d and %d kbps.", audio.MIN_BITRATE/1000, audio.MAX_BITRATE/1000))
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.Quality.Bitrate = &bitrate })
+
+	case "complexity":
+		complexity, err := strconv.Atoi(value)
+		if err != nil || complexity < 0 || complexity > 10 {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Complexity must be between 0 and 10.")
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.Quality.Complexity = &complexity })
+
+	case "fec":
+		if value != "on" && value != "off" {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ FEC must be `on` or `off`.")
+			return
+		}
+		fec := value == "on"
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.Quality.FEC = &fec })
+
+	case "loss":
+		loss, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil || loss < 0 || loss > 100 {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Packet loss must be between 0 and 100.")
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.Quality.PacketLoss = &loss })
+
+	default:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Unknown quality setting. Use `bitrate`, `complexity`, `fec`, `loss` or `reset`.")
+		return
+	}
+
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🎚️ Audio quality updated. It applies from the next track.")
+}
+
+func overrideMark(overridden bool) string {
+	if overridden {
+		return " (override)"
+	}
+	return ""
+}
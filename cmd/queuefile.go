@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"musicbot/audio"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxImportSize caps how much of an attached track list is downloaded.
+const maxImportSize = 1 << 20
+
+var importClient = &http.Client{Timeout: 15 * time.Second}
+
+// ExportQueue uploads the current track and queue as a file in format.
+func (cmd *BotCommand) ExportQueue(format string) {
+	queue := cmd.QueueManager.Get(cmd.Message.GuildID)
+
+	var tracks []*audio.Track
+	if current := queue.Current(); current != nil {
+		tracks = append(tracks, current)
+	}
+	tracks = append(tracks, queue.List()...)
+	if len(tracks) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🕳️ The queue is empty, nothing to export.")
+		return
+	}
+
+	data, err := audio.ExportTracks(tracks, audio.TrackListFormat(format))
+	if err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Use `m3u`, `xspf` or `json`.")
+		return
+	}
+
+	_, err = cmd.Session.ChannelMessageSendComplex(cmd.Message.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("📤 Exported %d tracks.", len(tracks)),
+		Files: []*discordgo.File{{
+			Name:   "queue." + format,
+			Reader: bytes.NewReader(data),
+		}},
+	})
+	if err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ Failed to upload the export: "+err.Error())
+	}
+}
+
+// ImportQueue enqueues the tracks in the track list attached to the
+// message, or the URLs listed after the command.
+func (cmd *BotCommand) ImportQueue(urls []string) {
+	var tracks []*audio.Track
+	for _, attachment := range cmd.Message.Attachments {
+		data, err := download(attachment.URL)
+		if err != nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ Failed to download "+attachment.Filename+": "+err.Error())
+			return
+		}
+		imported, err := audio.ImportTracks(attachment.Filename, data)
+		if err != nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ Couldn't read "+attachment.Filename+": "+err.Error())
+			return
+		}
+		tracks = append(tracks, imported...)
+	}
+	if len(urls) > 0 {
+		listed, _ := audio.ImportTracks("urls.m3u", []byte(strings.Join(urls, "\n")))
+		tracks = append(tracks, listed...)
+	}
+
+	if len(tracks) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "Usage: attach an M3U, XSPF or JSON file (or a list of URLs) to `>queue import`.")
+		return
+	}
+
+	voice, ok := cmd.ensureVoice()
+	if !ok {
+		return
+	}
+	if !cmd.enqueueBatch(tracks, "the import") {
+		return
+	}
+	cmd.startPlayback(voice)
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := importClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportSize {
+		return nil, fmt.Errorf("file is larger than %d KB", maxImportSize>>10)
+	}
+	return data, nil
+}
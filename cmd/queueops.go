@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"strconv"
+)
+
+// findLimit caps how many matches >queue find lists.
+const findLimit = 15
+
+// RemoveRange removes the tracks at positions from to to, inclusive.
+// Anyone but a DJ only removes their own.
+func (cmd *BotCommand) RemoveRange(from, to int) {
+	match := func(*audio.Track) bool { return true }
+	if !cmd.isDJ() {
+		match = cmd.requestedByAuthor
+	}
+
+	valid := false
+	var removed int
+	cmd.journaled("remove", func(e *audio.QueueEdit) {
+		valid = from >= 1 && from <= to && to <= len(e.Tracks())
+		removed = e.RemoveRangeWhere(from-1, to-1, match)
+	})
+	switch {
+	case !valid:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Invalid range.")
+	case removed == 0:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ None of those are yours, and only DJs can remove other people's tracks.")
+	case removed < to-from+1:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("❌ Removed %d of your tracks from positions %d-%d. Only DJs can remove other people's.", removed, from, to))
+	default:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("❌ Removed %d tracks (positions %d-%d).", removed, from, to))
+	}
+}
+
+// RemoveByUser removes every queued track userID requested. Only DJs can
+// remove someone else's.
+func (cmd *BotCommand) RemoveByUser(userID string) {
+	if userID != cmd.Message.Author.ID && !cmd.isDJ() {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ Only DJs can remove other people's tracks.")
+		return
+	}
+
+	var removed int
+	cmd.journaled("remove", func(e *audio.QueueEdit) {
+		removed = e.RemoveWhere(func(t *audio.Track) bool { return t.RequesterID == userID })
+	})
+
+	who := fmt.Sprintf("<@%s>", userID)
+	if userID == cmd.Message.Author.ID {
+		who = "you"
+	}
+	if removed == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🕳️ No queued tracks from "+who+".")
+		return
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("❌ Removed %d tracks queued by %s.", removed, who))
+}
+
+// Dedupe removes tracks that repeat an earlier one in the queue. Anyone
+// but a DJ only removes their own repeats.
+func (cmd *BotCommand) Dedupe() {
+	dj := cmd.isDJ()
+	match := func(*audio.Track) bool { return true }
+	if !dj {
+		match = cmd.requestedByAuthor
+	}
+
+	var removed int
+	cmd.journaled("dedupe", func(e *audio.QueueEdit) { removed = e.DedupeWhere(match) })
+	switch {
+	case removed == 0 && dj:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "✨ No duplicates in the queue.")
+	case removed == 0:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "✨ None of your tracks repeat one in the queue.")
+	case dj:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("🧹 Removed %d duplicate tracks.", removed))
+	default:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("🧹 Removed %d of your duplicate tracks.", removed))
+	}
+}
+
+func (cmd *BotCommand) requestedByAuthor(t *audio.Track) bool {
+	return t.RequesterID == cmd.Message.Author.ID
+}
+
+// FindInQueue lists the queued tracks matching text with their positions.
+func (cmd *BotCommand) FindInQueue(text string) {
+	var matches []*audio.Track
+	var positions []int
+	for i, t := range cmd.QueueManager.Get(cmd.Message.GuildID).List() {
+		if t.Matches(text) {
+			matches = append(matches, t)
+			positions = append(positions, i)
+		}
+	}
+	if len(matches) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔍 Nothing in the queue matches \""+text+"\".")
+		return
+	}
+
+	msg := fmt.Sprintf("🔍 %d matches:\n", len(matches))
+	for i, t := range matches {
+		if i == findLimit {
+			msg += fmt.Sprintf("…and %d more\n", len(matches)-findLimit)
+			break
+		}
+		msg += fmt.Sprintf("%d. %s\n", positions[i]+1, t.Title)
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
+
+// SkipTo discards the tracks before target and skips to it. target is a
+// position, or text to match as >queue find does, in which case the first
+// match is found in the same edit that skips to it.
+func (cmd *BotCommand) SkipTo(target string) {
+	guildID := cmd.Message.GuildID
+	if cmd.Settings.Get(guildID).VoteSkip && !cmd.isDJ() {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ Vote skip is on, so only DJs can skip ahead.")
+		return
+	}
+
+	n, err := strconv.Atoi(target)
+	var ok bool
+	var current *audio.Track
+	cmd.journaled("skip to", func(e *audio.QueueEdit) {
+		if err != nil {
+			positions := e.Find(target)
+			if len(positions) == 0 {
+				return
+			}
+			n = positions[0] + 1
+		}
+		current = e.Current()
+		ok = e.DropBefore(n - 1)
+	})
+	if !ok {
+		if err != nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔍 Nothing in the queue matches \""+target+"\".")
+			return
+		}
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Invalid position.")
+		return
+	}
+	if current == nil || cmd.Players.Get(guildID).SkipTrack(current) != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⏩ Removed the %d tracks before position %d.", n-1, n))
+		return
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⏩ Skipped to position %d.", n))
+}
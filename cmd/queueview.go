@@ -0,0 +1,241 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"musicbot/audio"
+	"musicbot/discord"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	queuePageSize    = 10
+	queueViewTimeout = 2 * time.Minute
+
+	// QueueButtonPrefix starts the custom ID of every >queue page button.
+	QueueButtonPrefix = "queue:"
+)
+
+// queueView is a >queue message whose page buttons still work. It expires
+// after queueViewTimeout without a button press.
+type queueView struct {
+	guildID   string
+	channelID string
+	messageID string
+	page      int
+	expiry    *time.Timer
+}
+
+// Queue posts the first page of the queue, with buttons to page through
+// the rest.
+func (cmd *BotCommand) Queue() {
+	guildID := cmd.Message.GuildID
+
+	embed, components, page := cmd.renderQueue(guildID, 0)
+	msg, err := cmd.Session.ChannelMessageSendComplex(cmd.Message.ChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	if err != nil {
+		fmt.Println("Failed to send queue:", err)
+		return
+	}
+	if len(components) == 0 {
+		return
+	}
+
+	view := &queueView{
+		guildID:   guildID,
+		channelID: msg.ChannelID,
+		messageID: msg.ID,
+		page:      page,
+	}
+	session := cmd.Session
+	view.expiry = time.AfterFunc(queueViewTimeout, func() {
+		cmd.expireQueueView(session, view)
+	})
+
+	cmd.mu.Lock()
+	cmd.queueViews[msg.ID] = view
+	cmd.mu.Unlock()
+}
+
+// expireQueueView forgets the view and removes its buttons.
+func (s *Services) expireQueueView(m discord.Messenger, view *queueView) {
+	s.mu.Lock()
+	delete(s.queueViews, view.messageID)
+	s.mu.Unlock()
+
+	edit := discordgo.NewMessageEdit(view.channelID, view.messageID)
+	edit.Components = &[]discordgo.MessageComponent{}
+	if _, err := m.ChannelMessageEditComplex(edit); err != nil {
+		fmt.Println("Failed to expire queue view:", err)
+	}
+}
+
+// QueueButton turns the page of a >queue message when one of its buttons
+// is pressed.
+func (s *Services) QueueButton(c discord.Client, i *discordgo.InteractionCreate) {
+	if i.Message == nil {
+		return
+	}
+
+	s.mu.Lock()
+	view, ok := s.queueViews[i.Message.ID]
+	if !ok {
+		s.mu.Unlock()
+		c.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "⌛ This queue view has expired. Use `>queue` again.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	switch strings.TrimPrefix(i.MessageComponentData().CustomID, QueueButtonPrefix) {
+	case "first":
+		view.page = 0
+	case "prev":
+		view.page--
+	case "next":
+		view.page++
+	case "last":
+		view.page = math.MaxInt
+	}
+	embed, components, page := s.renderQueue(view.guildID, view.page)
+	view.page = page
+	view.expiry.Reset(queueViewTimeout)
+	s.mu.Unlock()
+
+	err := c.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+	if err != nil {
+		fmt.Println("Failed to update queue view:", err)
+	}
+}
+
+// renderQueue builds the embed and page buttons for page, counted from 0
+// and clamped to the pages there are. It returns the page it rendered.
+func (s *Services) renderQueue(guildID string, page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent, int) {
+	queue := s.QueueManager.Get(guildID)
+	tracks := queue.List()
+	current := queue.Current()
+
+	pages := (len(tracks) + queuePageSize - 1) / queuePageSize
+	if pages == 0 {
+		pages = 1
+	}
+	page = max(0, min(page, pages-1))
+
+	timeline := queue.Timeline(s.Players.Get(guildID).Position())
+
+	desc := ""
+	if current != nil {
+		desc += "🎶 **Now Playing:** " + trackLink(current)
+		if current.Autoplay {
+			desc += " 📻"
+		}
+		if length, ok := current.Length(); ok {
+			elapsed := s.Players.Get(guildID).Position()
+			desc += fmt.Sprintf(" `%s/%s`", formatLength(elapsed), formatLength(length))
+		}
+		if name := requesterName(current); name != "" {
+			desc += " — " + name
+		}
+		desc += "\n\n"
+		if current.Autoplay {
+			desc += "📻 Autoplayed. Queue something to take over.\n\n"
+		}
+	} else {
+		desc += "📭 Nothing is currently playing.\n\n"
+	}
+
+	if len(tracks) == 0 {
+		desc += "🕳️ The queue is empty."
+	}
+	start := page * queuePageSize
+	end := min(start+queuePageSize, len(tracks))
+	for i := start; i < end; i++ {
+		t := tracks[i]
+		desc += fmt.Sprintf("`%d.` %s", i+1, trackLink(t))
+		if length, ok := t.Length(); ok {
+			desc += " `" + formatLength(length) + "`"
+		}
+		if name := requesterName(t); name != "" {
+			desc += " — " + name
+		}
+		if current != nil && i < len(timeline.Starts) {
+			desc += " · " + formatETA(timeline.Starts[i])
+		}
+		desc += "\n"
+	}
+
+	footer := fmt.Sprintf("Page %d/%d • %d tracks • %s remaining", page+1, pages, len(tracks), formatLength(timeline.Remaining))
+	if timeline.Unknown > 0 {
+		footer += fmt.Sprintf(" (+%d of unknown length)", timeline.Unknown)
+	}
+	footer += " • Loop: " + queue.GetLoopMode().String()
+	if queue.Fair() {
+		footer += " • Requesters take turns"
+	}
+	if queue.ShuffleMode() {
+		footer += " • Shuffle mode"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "🎼 Queue",
+		Description: desc,
+		Footer:      &discordgo.MessageEmbedFooter{Text: footer},
+	}
+	if pages == 1 {
+		return embed, nil, page
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			queueButton("first", "⏮️", page == 0),
+			queueButton("prev", "◀️", page == 0),
+			queueButton("next", "▶️", page == pages-1),
+			queueButton("last", "⏭️", page == pages-1),
+		}},
+	}
+	return embed, components, page
+}
+
+func queueButton(action, emoji string, disabled bool) discordgo.Button {
+	return discordgo.Button{
+		CustomID: QueueButtonPrefix + action,
+		Emoji:    &discordgo.ComponentEmoji{Name: emoji},
+		Style:    discordgo.SecondaryButton,
+		Disabled: disabled,
+	}
+}
+
+// trackLink is the track's title linked to its URL, shortened to keep a
+// page inside Discord's embed limits.
+func trackLink(t *audio.Track) string {
+	title := strings.NewReplacer("[", "(", "]", ")").Replace(t.Title)
+	if r := []rune(title); len(r) > 80 {
+		title = string(r[:79]) + "…"
+	}
+	return "[" + title + "](" + t.URL + ")"
+}
+
+// formatLength formats d like yt-dlp's duration_string.
+func formatLength(d time.Duration) string {
+	seconds := int(d.Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"strings"
+)
+
+func queueKey(guildID string) string {
+	return "queues/" + guildID
+}
+
+// SaveQueue persists the guild's queue, current track position and loop
+// mode, or removes the saved copy once there is nothing left to restore.
+// A queue still waiting for >restore is left untouched.
+func (s *Services) SaveQueue(guildID string) error {
+	s.mu.Lock()
+	_, pending := s.restores[guildID]
+	s.mu.Unlock()
+	if pending {
+		return nil
+	}
+
+	player := s.Players.Get(guildID)
+	snap := s.QueueManager.Get(guildID).Snapshot(player.Position())
+	if snap.Empty() {
+		return s.Store.Delete(queueKey(guildID))
+	}
+
+	if voice, ok := s.VoiceManager.Get(guildID); ok {
+		snap.VoiceChannelID = voice.ChannelID
+	}
+	snap.TextChannelID = player.TextChannel()
+	return s.Store.Save(queueKey(guildID), snap)
+}
+
+// LoadSavedQueues reads the queues saved before the last shutdown and holds
+// them until they are restored or discarded.
+func (s *Services) LoadSavedQueues() (map[string]*audio.QueueSnapshot, error) {
+	names, err := s.Store.List("queues")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		snap := &audio.QueueSnapshot{}
+		if _, err := s.Store.Load(name, snap); err != nil {
+			fmt.Println("Failed to load saved queue", name+":", err)
+			continue
+		}
+		if !snap.Empty() {
+			s.restores[strings.TrimPrefix(name, "queues/")] = snap
+		}
+	}
+
+	saved := make(map[string]*audio.QueueSnapshot, len(s.restores))
+	for guildID, snap := range s.restores {
+		saved[guildID] = snap
+	}
+	return saved, nil
+}
+
+// TakeRestore removes and returns the guild's queue awaiting >restore.
+func (s *Services) TakeRestore(guildID string) *audio.QueueSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := s.restores[guildID]
+	delete(s.restores, guildID)
+	return snap
+}
+
+func (s *Services) putRestore(guildID string, snap *audio.QueueSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restores[guildID] = snap
+}
+
+// Restore resumes the queue saved before the last restart, or drops it.
+func (cmd *BotCommand) Restore(discard bool) {
+	guildID := cmd.Message.GuildID
+
+	snap := cmd.TakeRestore(guildID)
+	if snap == nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "📭 There is no saved queue to restore.")
+		return
+	}
+
+	if discard {
+		_ = cmd.SaveQueue(guildID)
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🗑️ Discarded the saved queue.")
+		return
+	}
+
+	cmd.RestoreSnapshot(snap)
+}
+
+// RestoreSnapshot rejoins the saved voice channel (or the author's, if
+// none was saved) and resumes playback from the saved position.
+func (cmd *BotCommand) RestoreSnapshot(snap *audio.QueueSnapshot) {
+	guildID := cmd.Message.GuildID
+
+	voice, ok := cmd.VoiceManager.Get(guildID)
+	if !ok {
+		channelID := snap.VoiceChannelID
+		if channelID == "" {
+			channelID = cmd.getUserVoiceChannelID()
+		}
+		if channelID == "" {
+			cmd.putRestore(guildID, snap)
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔊 Join a voice channel, then use `>restore` again.")
+			return
+		}
+
+		var err error
+		voice, err = cmd.VoiceManager.Join(cmd.Session, guildID, channelID)
+		if err != nil {
+			cmd.putRestore(guildID, snap)
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ Failed to rejoin the voice channel: "+err.Error())
+			return
+		}
+	}
+
+	count := len(snap.Tracks)
+	if snap.Current != nil {
+		count++
+	}
+	cmd.QueueManager.Get(guildID).Restore(snap)
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("♻️ Restored %d tracks from before the restart.", count))
+
+	cmd.startPlayback(voice)
+}
@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/discord"
+	"musicbot/schedule"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Schedule handles >schedule add|list|remove. Only admins can change
+// schedules.
+func (cmd *BotCommand) Schedule(args []string) {
+	usage := "Usage: `>schedule add <minute> <hour> <day> <month> <weekday> <voice channel> <playlist|url>` (quote channel names with spaces), `>schedule list` or `>schedule remove <id>`"
+	if len(args) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, usage)
+		return
+	}
+	if args[0] == "list" {
+		cmd.listSchedules()
+		return
+	}
+
+	if !cmd.isAdmin() {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ Only server admins can change schedules.")
+		return
+	}
+
+	switch {
+	case args[0] == "add" && len(args) >= 8:
+		cmd.addSchedule(strings.Join(args[1:6], " "), args[6:])
+	case args[0] == "remove" && len(args) == 2:
+		id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+		if err != nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, usage)
+			return
+		}
+		if err := cmd.Schedules.Remove(cmd.Message.GuildID, id); err != nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("❌ No schedule #%d.", id))
+			return
+		}
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("🗑️ Removed schedule #%d.", id))
+	default:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, usage)
+	}
+}
+
+// addSchedule adds a schedule for expr. words are the voice channel then
+// the playlist or URL to play.
+func (cmd *BotCommand) addSchedule(expr string, words []string) {
+	guildID := cmd.Message.GuildID
+
+	voiceChannelID, channel, words := cmd.takeVoiceChannel(words)
+	if voiceChannelID == "" {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ No voice channel called "+channel+".")
+		return
+	}
+	if len(words) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ Say which playlist or URL to play in "+channel+".")
+		return
+	}
+	source := strings.Join(words, " ")
+	if !strings.Contains(source, "://") {
+		if _, err := cmd.Playlists.Find(cmd.Message.Author.ID, guildID, source); err != nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "❌ **"+source+"** is neither a URL nor one of your playlists.")
+			return
+		}
+	}
+
+	sc, err := cmd.Schedules.Add(schedule.Schedule{
+		GuildID:        guildID,
+		Cron:           expr,
+		VoiceChannelID: voiceChannelID,
+		TextChannelID:  cmd.Message.ChannelID,
+		Source:         source,
+		CreatedBy:      cmd.Message.Author.ID,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Bad cron expression: "+err.Error())
+		return
+	}
+
+	loc := cmd.Settings.Get(guildID).Location()
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("🗓️ Added schedule #%d. %s",
+		sc.ID, nextRun(sc, loc)))
+}
+
+func (cmd *BotCommand) listSchedules() {
+	schedules := cmd.Schedules.List(cmd.Message.GuildID)
+	if len(schedules) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🗓️ No schedules. Admins can add one with `>schedule add`.")
+		return
+	}
+
+	loc := cmd.Settings.Get(cmd.Message.GuildID).Location()
+	msg := fmt.Sprintf("🗓️ Schedules (%s time):\n", loc)
+	for _, sc := range schedules {
+		msg += fmt.Sprintf("#%d `%s` in <#%s>: %s. %s\n", sc.ID, sc.Cron, sc.VoiceChannelID, sc.Source, nextRun(sc, loc))
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
+
+func nextRun(sc *schedule.Schedule, loc *time.Location) string {
+	next := sc.Next(time.Now(), loc)
+	if next.IsZero() {
+		return "It never runs."
+	}
+	return "Next run: " + next.Format("Mon 2 Jan 15:04 MST")
+}
+
+// takeVoiceChannel finds the voice channel named at the start of words
+// and returns its ID, the name it was given by and the words after it. The
+// channel is a mention, an ID, a "quoted name" or a name of several words;
+// the longest name that matches wins, and at least one word is left over.
+// The ID is "" if no channel matches.
+func (cmd *BotCommand) takeVoiceChannel(words []string) (string, string, []string) {
+	if strings.HasPrefix(words[0], `"`) {
+		for i, w := range words {
+			if (i > 0 || len(w) > 1) && strings.HasSuffix(w, `"`) {
+				name := strings.Trim(strings.Join(words[:i+1], " "), `"`)
+				return cmd.findVoiceChannel(name), name, words[i+1:]
+			}
+		}
+	}
+	for n := len(words) - 1; n > 1; n-- {
+		name := strings.Join(words[:n], " ")
+		if id := cmd.findVoiceChannel(name); id != "" {
+			return id, name, words[n:]
+		}
+	}
+	return cmd.findVoiceChannel(words[0]), words[0], words[1:]
+}
+
+// findVoiceChannel returns the ID of the guild's voice channel given as a
+// mention, an ID or a name, or "" if there is none.
+func (cmd *BotCommand) findVoiceChannel(s string) string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "<#"), ">")
+	guild, err := cmd.Session.StateGuild(cmd.Message.GuildID)
+	if err != nil {
+		return ""
+	}
+	for _, c := range guild.Channels {
+		if c.Type != discordgo.ChannelTypeGuildVoice && c.Type != discordgo.ChannelTypeGuildStageVoice {
+			continue
+		}
+		if c.ID == s || strings.EqualFold(c.Name, s) {
+			return c.ID
+		}
+	}
+	return ""
+}
+
+// RunSchedule joins the schedule's voice channel and plays its source, as
+// if its creator had asked in the channel the schedule was added from.
+func (s *Services) RunSchedule(c discord.Client, sc *schedule.Schedule) {
+	m := &discordgo.MessageCreate{Message: &discordgo.Message{
+		GuildID:   sc.GuildID,
+		ChannelID: sc.TextChannelID,
+		Author:    &discordgo.User{ID: sc.CreatedBy},
+	}}
+	NewBotCommand(c, m, s).playScheduled(sc)
+}
+
+func (cmd *BotCommand) playScheduled(sc *schedule.Schedule) {
+	guildID := cmd.Message.GuildID
+
+	var tracks []*audio.Track
+	source := "the schedule"
+	switch {
+	case audio.IsPlaylistURL(sc.Source):
+		// Expanded once in voice, so the first track plays straight away.
+	case strings.Contains(sc.Source, "://"):
+		var err error
+		if tracks, _, err = cmd.resolveInput(sc.Source); err != nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("❌ Schedule #%d couldn't load %s: %s", sc.ID, sc.Source, err))
+			return
+		}
+	default:
+		p, err := cmd.Playlists.Find(sc.CreatedBy, guildID, sc.Source)
+		if err != nil || len(p.Tracks) == 0 {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("❌ Schedule #%d's playlist **%s** is gone or empty.", sc.ID, sc.Source))
+			return
+		}
+		tracks, source = p.Tracks, "playlist **"+p.Name+"**"
+	}
+
+	voice, ok := cmd.VoiceManager.Get(guildID)
+	if !ok {
+		var err error
+		voice, err = cmd.VoiceManager.Join(cmd.Session, guildID, sc.VoiceChannelID)
+		if err != nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("❌ Schedule #%d couldn't join <#%s>: %s", sc.ID, sc.VoiceChannelID, err))
+			return
+		}
+	}
+
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("🗓️ Running schedule #%d in <#%s>.", sc.ID, voice.ChannelID))
+	if tracks == nil {
+		cmd.expandPlaylist(voice, sc.Source, audio.PlaylistOptions{})
+		return
+	}
+	if !cmd.enqueueBatch(tracks, source) {
+		return
+	}
+	cmd.startPlayback(voice)
+}
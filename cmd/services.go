@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"musicbot/audio"
+	"musicbot/favorites"
+	"musicbot/playlist"
+	"musicbot/schedule"
+	"musicbot/settings"
+	"musicbot/stats"
+	"musicbot/store"
+	"musicbot/vc"
+	"sync"
+)
+
+// Services is the long-lived state shared by every command.
+type Services struct {
+	Bus          *audio.EventBus
+	VoiceManager *vc.VoiceManager
+	QueueManager *audio.QueueManager
+	Players      *audio.PlayerManager
+	Encoders     *audio.EncoderManager
+	Settings     *settings.Manager
+	History      *audio.HistoryManager
+	Journals     *audio.JournalManager
+	Playlists    *playlist.Manager
+	Favorites    *favorites.Manager
+	Stats        *stats.Manager
+	Schedules    *schedule.Manager
+	Store        *store.Store
+
+	mu         sync.Mutex
+	restores   map[string]*audio.QueueSnapshot // guildID → queue saved before restart
+	queueViews map[string]*queueView           // messageID → >queue message with live buttons
+	skipVotes  map[string]*skipVote            // guildID → vote to skip the current track
+	expansions map[string]*expansion           // guildID → playlists being expanded
+	audiences  map[string]map[string]bool      // guildID → listeners when the current track started
+}
+
+func NewServices(st *store.Store) (*Services, error) {
+	playlists, err := playlist.NewManager(st)
+	if err != nil {
+		return nil, err
+	}
+
+	likes, err := favorites.NewManager(st)
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := schedule.NewManager(st)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := audio.NewEventBus()
+	guildSettings := settings.NewManager(st)
+	queues := audio.NewQueueManager(bus)
+	queues.OnCreate(func(q *audio.Queue) {
+		q.SetFair(guildSettings.Get(q.GuildID()).FairQueue)
+	})
+	s := &Services{
+		Bus:          bus,
+		VoiceManager: vc.NewVoiceManager(bus),
+		QueueManager: queues,
+		Encoders:     audio.NewEncoderManager(),
+		Settings:     guildSettings,
+		History:      audio.NewHistoryManager(st),
+		Journals:     audio.NewJournalManager(),
+		Playlists:    playlists,
+		Favorites:    likes,
+		Stats:        stats.NewManager(st),
+		Schedules:    schedules,
+		Store:        st,
+		restores:     make(map[string]*audio.QueueSnapshot),
+		queueViews:   make(map[string]*queueView),
+		skipVotes:    make(map[string]*skipVote),
+		expansions:   make(map[string]*expansion),
+		audiences:    make(map[string]map[string]bool),
+	}
+	s.Players = audio.NewPlayerManager(queues, ResolveTrack, s.Recommend, bus)
+	return s, nil
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/settings"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GuildSettings shows the guild's settings or changes one of them.
+func (cmd *BotCommand) GuildSettings(args []string) {
+	guildID := cmd.Message.GuildID
+
+	if len(args) == 0 {
+		gs := cmd.Settings.Get(guildID)
+		djRole := "none"
+		if gs.DJRoleID != "" {
+			djRole = "<@&" + gs.DJRoleID + ">"
+		}
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf(
+			"⚙️ Settings:\n• Auto-restore queue after restart: %s\n• Fair queue: %s\n• DJ role: %s\n• Autoplay: %s, avoiding the last %d tracks\n• Vote skip: %s, needing %d%% of listeners\n• Playlist cap: %d tracks\n• Time zone: %s",
+			onOff(gs.AutoRestore), onOff(gs.FairQueue), djRole, onOff(gs.Autoplay), gs.RepeatWindow(), onOff(gs.VoteSkip), gs.SkipPercent(), gs.ExpandCap(), gs.Location()))
+		return
+	}
+
+	if !cmd.isAdmin() {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ Only server admins can change settings.")
+		return
+	}
+
+	if len(args) != 2 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "Usage: `>settings autorestore|fairqueue on|off`, `>settings djrole <@role>|none`, `>settings autoplaywindow <tracks>`, `>settings voteskip on|off`, `>settings voteskippercent <1-100>`, `>settings playlistcap <tracks>` or `>settings timezone <zone>`")
+		return
+	}
+
+	switch args[0] {
+	case "autorestore":
+		enabled, ok := parseOnOff(args[1])
+		if !ok {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Use `on` or `off`.")
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.AutoRestore = enabled })
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚙️ Auto-restore is now "+onOff(enabled)+".")
+
+	case "fairqueue":
+		enabled, ok := parseOnOff(args[1])
+		if !ok {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Use `on` or `off`.")
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.FairQueue = enabled })
+		cmd.QueueManager.Get(guildID).SetFair(enabled)
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚙️ Fair queue is now "+onOff(enabled)+".")
+
+	case "djrole":
+		roleID := strings.TrimSuffix(strings.TrimPrefix(args[1], "<@&"), ">")
+		if roleID == "none" {
+			roleID = ""
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.DJRoleID = roleID })
+		if roleID == "" {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚙️ DJ role removed.")
+		} else {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚙️ DJ role set to <@&"+roleID+">.")
+		}
+
+	case "autoplaywindow":
+		window, err := strconv.Atoi(args[1])
+		if err != nil || window < 0 || window > audio.MAX_HISTORY {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⚠️ The window must be between 0 and %d tracks.", audio.MAX_HISTORY))
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.AutoplayWindow = &window })
+		if window == 0 {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚙️ Autoplay may repeat recent tracks, just not the one that just played.")
+			return
+		}
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⚙️ Autoplay won't repeat any of the last %d tracks.", window))
+
+	case "voteskip":
+		enabled, ok := parseOnOff(args[1])
+		if !ok {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Use `on` or `off`.")
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.VoteSkip = enabled })
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚙️ Vote skip is now "+onOff(enabled)+".")
+
+	case "voteskippercent":
+		percent, err := strconv.Atoi(strings.TrimSuffix(args[1], "%"))
+		if err != nil || percent < 1 || percent > 100 {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ The threshold must be between 1 and 100%.")
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.VoteSkipPercent = percent })
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⚙️ Vote skip now needs %d%% of listeners.", percent))
+
+	case "playlistcap":
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Use a positive number of tracks.")
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.PlaylistCap = n })
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⚙️ At most %d tracks of a playlist are queued at once.", n))
+
+	case "timezone":
+		loc, err := time.LoadLocation(args[1])
+		if err != nil {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Unknown time zone. Use a name like `Europe/Berlin` or `America/New_York`.")
+			return
+		}
+		cmd.Settings.Update(guildID, func(gs *settings.GuildSettings) { gs.Timezone = loc.String() })
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚙️ Schedules now run in "+loc.String()+" time.")
+
+	default:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Unknown setting.")
+	}
+}
+
+func parseOnOff(s string) (bool, bool) {
+	switch s {
+	case "on":
+		return true, true
+	case "off":
+		return false, true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"time"
+)
+
+// Sleep handles >sleep: a duration (optionally counting only play time),
+// "end" for the end of the current track, "cancel" or "status".
+func (cmd *BotCommand) Sleep(args []string) {
+	player := cmd.Players.Get(cmd.Message.GuildID)
+	usage := "Usage: `>sleep <duration> [playtime]`, `>sleep end|cancel|status`"
+
+	if len(args) == 0 || args[0] == "status" {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, sleepText(player.SleepStatus()))
+		return
+	}
+
+	var err error
+	switch args[0] {
+	case "cancel":
+		err = player.CancelSleep()
+	case "end":
+		err = player.SleepAfterTrack()
+	default:
+		d, ok := parseLength(args[0])
+		if !ok || d <= 0 || len(args) > 2 || (len(args) == 2 && args[1] != "playtime") {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, usage)
+			return
+		}
+		err = player.Sleep(d, len(args) == 2)
+	}
+
+	switch {
+	case err == audio.ErrNoSleep:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "😴 No sleep timer is set.")
+	case err != nil:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⚠️ Nothing is playing.")
+	case args[0] == "cancel":
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⏰ Sleep timer cancelled.")
+	default:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, sleepText(player.SleepStatus()))
+	}
+}
+
+func sleepText(status *audio.SleepStatus) string {
+	switch {
+	case status == nil:
+		return "😴 No sleep timer is set."
+	case status.AfterTrack:
+		return "😴 Stopping after the current track."
+	}
+
+	msg := fmt.Sprintf("😴 Stopping and leaving in %s", status.Left.Round(time.Second))
+	if status.PlayTime {
+		msg += " of play time"
+		if !status.Running {
+			msg += " (on hold while nothing plays)"
+		}
+	}
+	return msg + "."
+}
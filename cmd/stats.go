@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/discord"
+	"time"
+)
+
+// topSize is how many entries the >top charts list.
+const topSize = 10
+
+// RecordStats counts each played track towards the guild's stats. The
+// people listening are taken when a track starts, since the bot may have
+// left voice by the time it has ended.
+func (s *Services) RecordStats(state discord.GuildState, ev audio.Event) {
+	switch e := ev.(type) {
+	case audio.TrackStarted:
+		listeners := s.listeners(state, e.GuildID)
+		s.mu.Lock()
+		s.audiences[e.GuildID] = listeners
+		s.mu.Unlock()
+
+	case audio.TrackEnded:
+		if e.Reason == audio.EndError {
+			return
+		}
+		s.mu.Lock()
+		audience := s.audiences[e.GuildID]
+		delete(s.audiences, e.GuildID)
+		s.mu.Unlock()
+
+		var listeners []string
+		for userID := range audience {
+			listeners = append(listeners, userID)
+		}
+		now := time.Now().In(s.Settings.Get(e.GuildID).Location())
+		s.Stats.RecordPlay(e.GuildID, e.Track, e.Reason, e.Played, listeners, now)
+	}
+}
+
+// ShowStats shows the guild's totals, or the author's with "me".
+func (cmd *BotCommand) ShowStats(me bool) {
+	guildID := cmd.Message.GuildID
+	if me {
+		c := cmd.Stats.User(guildID, cmd.Message.Author.ID)
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf(
+			"📊 Your stats:\n• Tracks queued: %d\n• Of those, played through: %d, skipped: %d\n• Time listened: %s",
+			c.Requests, c.Plays, c.Skips, formatHours(c.Listened)))
+		return
+	}
+
+	c := cmd.Stats.Guild(guildID)
+	msg := fmt.Sprintf("📊 Server stats:\n• Tracks played through: %d\n• Skipped: %d\n• Tracks queued: %d\n• Time played: %s",
+		c.Plays, c.Skips, c.Requests, formatHours(c.Listened))
+	if top := cmd.Stats.TopTracks(guildID, 0, time.Now(), 1); len(top) > 0 {
+		msg += fmt.Sprintf("\n• Most played: %s (%d plays)", top[0].Title, top[0].Plays)
+	}
+	if top := cmd.Stats.TopRequesters(guildID, 1); len(top) > 0 {
+		msg += fmt.Sprintf("\n• Top requester: <@%s> (%d tracks)", top[0].ID, top[0].Requests)
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
+
+// TopTracks lists the most played tracks over period: "week", "month" or
+// "all". Skipped plays don't count.
+func (cmd *BotCommand) TopTracks(period string) {
+	days := map[string]int{"week": 7, "month": 30, "all": 0}
+	n, ok := days[period]
+	if !ok {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "Usage: `>top tracks [week|month|all]`")
+		return
+	}
+
+	now := time.Now().In(cmd.Settings.Get(cmd.Message.GuildID).Location())
+	top := cmd.Stats.TopTracks(cmd.Message.GuildID, n, now, topSize)
+	if len(top) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "📊 Nothing has been played through yet.")
+		return
+	}
+
+	title := map[string]string{"week": "this week", "month": "this month", "all": "of all time"}[period]
+	msg := "🏆 Top tracks " + title + ":\n"
+	for i, r := range top {
+		msg += fmt.Sprintf("%d. %s — %d plays", i+1, r.Title, r.Plays)
+		if period == "all" && r.Skips > 0 {
+			msg += fmt.Sprintf(", %d skips", r.Skips)
+		}
+		msg += "\n"
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
+
+// TopRequesters lists who queued the most tracks.
+func (cmd *BotCommand) TopRequesters() {
+	top := cmd.Stats.TopRequesters(cmd.Message.GuildID, topSize)
+	if len(top) == 0 {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "📊 Nobody has queued anything yet.")
+		return
+	}
+
+	msg := "🏆 Top requesters:\n"
+	for i, r := range top {
+		msg += fmt.Sprintf("%d. <@%s> — %d tracks (%d played through, %d skipped)\n", i+1, r.ID, r.Requests, r.Plays, r.Skips)
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, msg)
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"errors"
+	"musicbot/audio"
+	"musicbot/discord"
+	"musicbot/stats"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestRecordStats(t *testing.T) {
+	s := newTestServices(t)
+	fake := discord.NewFake("bot")
+	fake.AddChannel("guild", &discordgo.Channel{ID: "voice", Type: discordgo.ChannelTypeGuildVoice})
+	if _, err := s.VoiceManager.Join(fake, "guild", "voice"); err != nil {
+		t.Fatal(err)
+	}
+	fake.SetVoiceState("guild", "alice", "voice")
+
+	track := &audio.Track{URL: "https://youtu.be/a", Title: "A", RequesterID: "bob"}
+	s.RecordStats(fake, audio.TrackStarted{GuildID: "guild", Track: track})
+	// Who listened is who was there when the track started.
+	fake.SetVoiceState("guild", "carol", "voice")
+	s.RecordStats(fake, audio.TrackEnded{GuildID: "guild", Track: track, Reason: audio.EndFinished, Played: time.Minute})
+
+	// A track that failed isn't counted.
+	s.RecordStats(fake, audio.TrackStarted{GuildID: "guild", Track: track})
+	s.RecordStats(fake, audio.TrackEnded{GuildID: "guild", Track: track, Reason: audio.EndError, Err: errors.New("boom")})
+
+	if got, want := s.Stats.Guild("guild"), (stats.Counts{Plays: 1, Listened: time.Minute}); got != want {
+		t.Errorf("guild counts = %+v, want %+v", got, want)
+	}
+	for userID, want := range map[string]stats.Counts{
+		"alice": {Listened: time.Minute},
+		"bob":   {Plays: 1},
+		"carol": {},
+	} {
+		if got := s.Stats.User("guild", userID); got != want {
+			t.Errorf("%s's counts = %+v, want %+v", userID, got, want)
+		}
+	}
+}
+
+func TestFormatHours(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                               "0m",
+		59*time.Minute + 59*time.Second: "59m",
+		time.Hour:                       "1h00m",
+		12*time.Hour + 5*time.Minute:    "12h05m",
+		150 * time.Hour:                 "150h00m",
+	} {
+		if got := formatHours(d); got != want {
+			t.Errorf("formatHours(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"time"
+)
+
+// journaled makes change, an edit of the queue by the author described by
+// action, and records the queue as it was before so >undo can restore it.
+// Edits that change nothing are not recorded. The queue stays locked from
+// reading the state before to recording it, so nothing can slip in between.
+func (cmd *BotCommand) journaled(action string, change func(e *audio.QueueEdit)) {
+	cmd.QueueManager.Get(cmd.Message.GuildID).Edit(func(e *audio.QueueEdit) {
+		before, mode := e.Tracks(), e.LoopMode()
+		change(e)
+		after := e.Tracks()
+		if mode == e.LoopMode() && sameTracks(before, after) {
+			return
+		}
+		cmd.record(action, before, mode, after)
+	})
+}
+
+func (cmd *BotCommand) record(action string, tracks []*audio.Track, mode audio.LoopMode, after []*audio.Track) {
+	cmd.Journals.Get(cmd.Message.GuildID).Record(audio.JournalEntry{
+		Action:   action,
+		UserID:   cmd.Message.Author.ID,
+		At:       time.Now(),
+		Tracks:   tracks,
+		LoopMode: mode,
+		After:    after,
+	})
+}
+
+// journaledStop records the queue with the playing track at its head, so
+// undoing >stop or >leave brings back what was playing too.
+func (cmd *BotCommand) journaledStop(action string) {
+	guildID := cmd.Message.GuildID
+	snap := cmd.QueueManager.Get(guildID).Snapshot(cmd.Players.Get(guildID).Position())
+	tracks := snap.Tracks
+	if snap.Current != nil {
+		tracks = append([]*audio.Track{snap.Current}, tracks...)
+	}
+	if len(tracks) > 0 {
+		cmd.record(action, tracks, snap.LoopMode, nil)
+	}
+}
+
+func sameTracks(a, b []*audio.Track) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Undo reverts the guild's last queue edit.
+func (cmd *BotCommand) Undo() {
+	entry, ok := cmd.revert(false)
+	if ok {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("↩️ Undid %s by <@%s> <t:%d:R>.", entry.Action, entry.UserID, entry.At.Unix()))
+		cmd.resumeAfterRestore()
+	}
+}
+
+// Redo reapplies the last queue edit undone with >undo.
+func (cmd *BotCommand) Redo() {
+	entry, ok := cmd.revert(true)
+	if ok {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("↪️ Redid %s by <@%s>.", entry.Action, entry.UserID))
+		cmd.resumeAfterRestore()
+	}
+}
+
+// revert undoes the guild's last queue edit, or redoes the last one
+// undone, if the author may. It tells the author if there is nothing to
+// revert or they may not.
+func (cmd *BotCommand) revert(redo bool) (audio.JournalEntry, bool) {
+	guildID := cmd.Message.GuildID
+	queue := cmd.QueueManager.Get(guildID)
+	journal := cmd.Journals.Get(guildID)
+	pop, verb, icon := journal.Undo, "undo", "↩️"
+	if redo {
+		pop, verb, icon = journal.Redo, "redo", "↪️"
+	}
+	dj, fair, voteSkip := cmd.isDJ(), queue.Fair(), cmd.Settings.Get(guildID).VoteSkip
+
+	var refusal string
+	allow := func(entry audio.JournalEntry) bool {
+		refusal = revertRefusal(entry, cmd.Message.Author.ID, dj, fair, voteSkip)
+		return refusal == ""
+	}
+	var entry audio.JournalEntry
+	var err error
+	queue.Edit(func(e *audio.QueueEdit) {
+		now := audio.JournalEntry{Tracks: e.Tracks(), LoopMode: e.LoopMode(), At: time.Now()}
+		entry, err = pop(now, allow)
+		if err == nil {
+			e.SetState(entry.Tracks, entry.LoopMode)
+		}
+	})
+
+	switch err {
+	case nil:
+		// A playlist still loading would queue the rest of what was undone.
+		if entry.Action == "playlist" {
+			cmd.CancelExpansions(guildID)
+		}
+		return entry, true
+	case audio.ErrJournalEmpty:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, icon+" Nothing to "+verb+".")
+	default:
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "⛔ "+refusal+" can "+verb+" "+entry.Action+".")
+	}
+	return entry, false
+}
+
+// revertRefusal says who may undo or redo entry, if userID may not. Only
+// DJs may revert someone else's edit, and an edit only DJs could have
+// made, such as a move in fair mode, needs a DJ to revert it too.
+func revertRefusal(entry audio.JournalEntry, userID string, dj, fair, voteSkip bool) string {
+	if dj {
+		return ""
+	}
+	if entry.UserID != userID {
+		return fmt.Sprintf("Only DJs and <@%s>", entry.UserID)
+	}
+	switch entry.Action {
+	case "insert", "move", "play next":
+		if fair {
+			return "Fair queue is on, so only DJs"
+		}
+	case "play now":
+		if fair || voteSkip {
+			return "Only DJs"
+		}
+	case "skip to":
+		if voteSkip {
+			return "Vote skip is on, so only DJs"
+		}
+	}
+	return ""
+}
+
+// resumeAfterRestore starts playback again if an undo brought back tracks
+// after playback had stopped, as long as the author is in voice to hear it.
+func (cmd *BotCommand) resumeAfterRestore() {
+	if cmd.Players.Get(cmd.Message.GuildID).State() != audio.StateIdle || cmd.getUserVoiceChannelID() == "" {
+		return
+	}
+	if tracks, _ := cmd.QueueManager.Get(cmd.Message.GuildID).State(); len(tracks) == 0 {
+		return
+	}
+	if voice, ok := cmd.ensureVoice(); ok {
+		cmd.startPlayback(voice)
+	}
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"musicbot/audio"
+	"testing"
+)
+
+func TestRevertRefusal(t *testing.T) {
+	for _, tt := range []struct {
+		action, by         string
+		dj, fair, voteSkip bool
+		allowed            bool
+	}{
+		{action: "clear", by: "alice", allowed: true},
+		{action: "clear", by: "bob"},
+		{action: "clear", by: "bob", dj: true, allowed: true},
+		{action: "move", by: "alice", allowed: true},
+		{action: "move", by: "alice", fair: true},
+		{action: "move", by: "alice", fair: true, dj: true, allowed: true},
+		{action: "play now", by: "alice", voteSkip: true},
+		{action: "skip to", by: "alice", fair: true, allowed: true},
+		{action: "skip to", by: "alice", voteSkip: true},
+	} {
+		entry := audio.JournalEntry{Action: tt.action, UserID: tt.by}
+		refusal := revertRefusal(entry, "alice", tt.dj, tt.fair, tt.voteSkip)
+		if (refusal == "") != tt.allowed {
+			t.Errorf("%+v: refusal = %q", tt, refusal)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/discord"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// skipVote is the vote to skip a guild's current track.
+type skipVote struct {
+	track  *audio.Track    // the track being voted on
+	voters map[string]bool // userID → voted
+}
+
+// skipError explains why skipping a track failed.
+func skipError(err error) string {
+	if err == audio.ErrTrackChanged {
+		return "⏭️ That track already ended."
+	}
+	return "❌ Nothing is currently playing."
+}
+
+// listeners returns the IDs of the people, not bots, in the bot's voice
+// channel in guildID.
+func (s *Services) listeners(state discord.GuildState, guildID string) map[string]bool {
+	voice, ok := s.VoiceManager.Get(guildID)
+	if !ok {
+		return nil
+	}
+	guild, err := state.StateGuild(guildID)
+	if err != nil {
+		return nil
+	}
+
+	out := make(map[string]bool)
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID != voice.ChannelID || vs.UserID == state.BotUserID() {
+			continue
+		}
+		// Voice states from the gateway often leave out the member.
+		member := vs.Member
+		if member == nil {
+			member, _ = state.StateMember(guildID, vs.UserID)
+		}
+		if member != nil && member.User != nil && member.User.Bot {
+			continue
+		}
+		out[vs.UserID] = true
+	}
+	return out
+}
+
+// votesNeeded is how many of listeners must vote to skip.
+func (s *Services) votesNeeded(guildID string, listeners int) int {
+	percent := s.Settings.Get(guildID).SkipPercent()
+	return max(1, (listeners*percent+99)/100)
+}
+
+// countSkipVotes drops votes from people who are no longer listening and
+// returns how many remain.
+func (s *Services) countSkipVotes(guildID string, listeners map[string]bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vote, ok := s.skipVotes[guildID]
+	if !ok {
+		return 0
+	}
+	for userID := range vote.voters {
+		if !listeners[userID] {
+			delete(vote.voters, userID)
+		}
+	}
+	return len(vote.voters)
+}
+
+// ResetSkipVotes clears the guild's skip vote when a new track starts.
+func (s *Services) ResetSkipVotes(ev audio.TrackStarted) {
+	s.clearSkipVote(ev.GuildID)
+}
+
+func (s *Services) clearSkipVote(guildID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.skipVotes, guildID)
+}
+
+// VoiceStateChanged re-checks a running skip vote when someone joins or
+// leaves voice, since fewer listeners may now be enough to pass it.
+func (s *Services) VoiceStateChanged(c discord.Client, v *discordgo.VoiceStateUpdate) {
+	guildID := v.GuildID
+
+	s.mu.Lock()
+	vote, voting := s.skipVotes[guildID]
+	s.mu.Unlock()
+	if !voting {
+		return
+	}
+
+	listeners := s.listeners(c, guildID)
+	votes := s.countSkipVotes(guildID, listeners)
+	needed := s.votesNeeded(guildID, len(listeners))
+	if votes == 0 || votes < needed {
+		return
+	}
+
+	s.clearSkipVote(guildID)
+	player := s.Players.Get(guildID)
+	if err := player.SkipTrack(vote.track); err != nil {
+		return
+	}
+	c.ChannelMessageSend(player.TextChannel(), fmt.Sprintf("⏭️ Vote passed (%d/%d). Skipped current track.", votes, needed))
+}
+
+// voteSkip records the author's vote to skip current and skips it once
+// enough of the listeners agree.
+func (cmd *BotCommand) voteSkip(current *audio.Track) {
+	guildID := cmd.Message.GuildID
+	voice, ok := cmd.VoiceManager.Get(guildID)
+	if !ok || cmd.getUserVoiceChannelID() != voice.ChannelID {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, "🔊 You must be in my voice channel to vote.")
+		return
+	}
+
+	cmd.mu.Lock()
+	vote, ok := cmd.skipVotes[guildID]
+	if !ok || vote.track != current {
+		// Votes for a track that has ended don't carry over.
+		vote = &skipVote{track: current, voters: make(map[string]bool)}
+		cmd.skipVotes[guildID] = vote
+	}
+	already := vote.voters[cmd.Message.Author.ID]
+	vote.voters[cmd.Message.Author.ID] = true
+	cmd.mu.Unlock()
+
+	listeners := cmd.listeners(cmd.Session, guildID)
+	votes := cmd.countSkipVotes(guildID, listeners)
+	needed := cmd.votesNeeded(guildID, len(listeners))
+
+	if votes < needed {
+		if already {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("🗳️ You already voted. %d/%d votes to skip.", votes, needed))
+		} else {
+			cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("🗳️ Vote to skip: %d/%d.", votes, needed))
+		}
+		return
+	}
+
+	cmd.clearSkipVote(guildID)
+	if err := cmd.Players.Get(guildID).SkipTrack(current); err != nil {
+		cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, skipError(err))
+		return
+	}
+	cmd.Session.ChannelMessageSend(cmd.Message.ChannelID, fmt.Sprintf("⏭️ Vote passed (%d/%d). Skipped current track.", votes, needed))
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"musicbot/audio"
+	"musicbot/discord"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestListenersLeaveOutBots(t *testing.T) {
+	s := newTestServices(t)
+	fake := discord.NewFake("bot")
+	fake.AddChannel("guild", &discordgo.Channel{ID: "voice", Type: discordgo.ChannelTypeGuildVoice})
+	fake.AddChannel("guild", &discordgo.Channel{ID: "other", Type: discordgo.ChannelTypeGuildVoice})
+	if _, err := s.VoiceManager.Join(fake, "guild", "voice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The gateway's voice states carry no member, so whether someone is a
+	// bot comes from the member cache.
+	fake.AddMember("guild", &discordgo.Member{User: &discordgo.User{ID: "alice"}})
+	fake.AddMember("guild", &discordgo.Member{User: &discordgo.User{ID: "otherbot", Bot: true}})
+	for _, userID := range []string{"bot", "alice", "otherbot", "carol"} {
+		fake.SetVoiceState("guild", userID, "voice")
+	}
+	fake.SetVoiceState("guild", "dave", "other")
+
+	got := s.listeners(fake, "guild")
+	if len(got) != 2 || !got["alice"] || !got["carol"] {
+		t.Errorf("listeners = %v, want alice and carol", got)
+	}
+}
+
+func TestSkipVoteOnlySkipsItsTrack(t *testing.T) {
+	fakeTool(t, "yt-dlp", "")
+	fakeTool(t, "ffmpeg", "exec sleep 60")
+	s := newTestServices(t)
+	fake := discord.NewFake("bot")
+	fake.AddChannel("guild", &discordgo.Channel{ID: "voice", Type: discordgo.ChannelTypeGuildVoice})
+	if _, err := s.VoiceManager.Join(fake, "guild", "voice"); err != nil {
+		t.Fatal(err)
+	}
+	fake.SetVoiceState("guild", "alice", "voice")
+
+	queue := s.QueueManager.Get("guild")
+	queue.EnqueueMultiple([]*audio.Track{
+		{URL: "https://youtu.be/a", Title: "A", Duration: "3:00"},
+		{URL: "https://youtu.be/b", Title: "B", Duration: "3:00"},
+	})
+	player := s.Players.Get("guild")
+	player.SetTextChannel("text")
+	player.Attach(audio.NewNullSink(), func() (*audio.Encoder, error) {
+		return audio.NewEncoder(audio.EncoderSettingsForBitrate(0))
+	})
+	t.Cleanup(player.Stop)
+	playing := func(title string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for current := queue.Current(); current == nil || current.Title != title || player.State() != audio.StatePlaying; current = queue.Current() {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s isn't playing", title)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	vote := func(track *audio.Track) {
+		s.mu.Lock()
+		s.skipVotes["guild"] = &skipVote{track: track, voters: map[string]bool{"alice": true}}
+		s.mu.Unlock()
+		s.VoiceStateChanged(fake, &discordgo.VoiceStateUpdate{VoiceState: &discordgo.VoiceState{GuildID: "guild"}})
+	}
+
+	player.Start()
+	playing("A")
+	a := queue.Current()
+	if err := player.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	playing("B")
+	b := queue.Current()
+
+	// A vote cast on A that passes once B has started leaves B alone.
+	vote(a)
+	if queue.Current() != b || len(fake.Messages("text")) != 0 {
+		t.Errorf("a vote on A skipped B: %v", fake.Messages("text"))
+	}
+
+	vote(b)
+	if msgs := fake.Messages("text"); len(msgs) != 1 || msgs[0] != "⏭️ Vote passed (1/1). Skipped current track." {
+		t.Errorf("a vote on B said %v", msgs)
+	}
+}
@@ -0,0 +1,76 @@
+package discord
+
+import (
+	"musicbot/audio"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Messenger sends and edits channel messages.
+type Messenger interface {
+	ChannelMessageSend(channelID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEdit(channelID, messageID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// Interactions answers button presses and other component interactions.
+type Interactions interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+}
+
+// GuildState looks up cached guild and channel state.
+type GuildState interface {
+	BotUserID() string
+	StateGuild(guildID string) (*discordgo.Guild, error)
+	StateChannel(channelID string) (*discordgo.Channel, error)
+	StateMember(guildID, userID string) (*discordgo.Member, error)
+	UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error)
+}
+
+// VoiceJoiner connects the bot to a voice channel.
+type VoiceJoiner interface {
+	JoinVoice(guildID, channelID string) (audio.AudioSink, error)
+}
+
+// Client is every Discord operation the bot uses.
+type Client interface {
+	Messenger
+	Interactions
+	GuildState
+	VoiceJoiner
+	AddHandler(handler interface{}) func()
+}
+
+// Session adapts a live *discordgo.Session to Client.
+type Session struct {
+	*discordgo.Session
+}
+
+func NewSession(s *discordgo.Session) *Session {
+	return &Session{Session: s}
+}
+
+func (s *Session) BotUserID() string {
+	return s.State.User.ID
+}
+
+func (s *Session) StateGuild(guildID string) (*discordgo.Guild, error) {
+	return s.State.Guild(guildID)
+}
+
+func (s *Session) StateChannel(channelID string) (*discordgo.Channel, error) {
+	return s.State.Channel(channelID)
+}
+
+func (s *Session) StateMember(guildID, userID string) (*discordgo.Member, error) {
+	return s.State.Member(guildID, userID)
+}
+
+func (s *Session) JoinVoice(guildID, channelID string) (audio.AudioSink, error) {
+	vc, err := s.ChannelVoiceJoin(guildID, channelID, false, true)
+	if err != nil {
+		return nil, err
+	}
+	return audio.NewDiscordSink(vc), nil
+}
@@ -0,0 +1,390 @@
+package discord
+
+import (
+	"errors"
+	"io"
+	"musicbot/audio"
+	"strconv"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SentMessage is a message recorded by Fake.
+type SentMessage struct {
+	ID         string
+	ChannelID  string
+	Content    string
+	Embeds     []*discordgo.MessageEmbed
+	Components []discordgo.MessageComponent
+	Files      []FakeFile
+	Edited     bool
+}
+
+// FakeFile is a file attached to a message recorded by Fake.
+type FakeFile struct {
+	Name    string
+	Content []byte
+}
+
+// InteractionResponse is a response to an interaction recorded by Fake.
+type InteractionResponse struct {
+	InteractionID string
+	Response      *discordgo.InteractionResponse
+}
+
+// VoiceJoin is a voice join recorded by Fake.
+type VoiceJoin struct {
+	GuildID   string
+	ChannelID string
+}
+
+// Fake is an in-memory Client for driving the bot without a gateway.
+type Fake struct {
+	mu          sync.Mutex
+	botID       string
+	guilds      map[string]*discordgo.Guild
+	channels    map[string]*discordgo.Channel
+	permissions map[string]int64 // userID → permissions
+	messages    []*SentMessage
+	responses   []InteractionResponse
+	joins       []VoiceJoin
+	handlers    []*fakeHandler
+	nextID      int
+
+	// NewSink creates the sink returned by JoinVoice. Defaults to a NullSink.
+	NewSink func(guildID, channelID string) (audio.AudioSink, error)
+}
+
+// fakeHandler holds one of the handler kinds Fake can dispatch to.
+type fakeHandler struct {
+	message     func(*discordgo.Session, *discordgo.MessageCreate)
+	interaction func(*discordgo.Session, *discordgo.InteractionCreate)
+	voiceState  func(*discordgo.Session, *discordgo.VoiceStateUpdate)
+}
+
+func NewFake(botID string) *Fake {
+	return &Fake{
+		botID:       botID,
+		guilds:      make(map[string]*discordgo.Guild),
+		channels:    make(map[string]*discordgo.Channel),
+		permissions: make(map[string]int64),
+		NewSink: func(string, string) (audio.AudioSink, error) {
+			return audio.NewNullSink(), nil
+		},
+	}
+}
+
+// AddChannel registers a channel in guildID, creating the guild if needed.
+func (f *Fake) AddChannel(guildID string, channel *discordgo.Channel) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	channel.GuildID = guildID
+	f.channels[channel.ID] = channel
+	f.guild(guildID).Channels = append(f.guild(guildID).Channels, channel)
+}
+
+// SetVoiceState moves userID into channelID, or out of voice when it is
+// empty, and notifies voice state handlers as the gateway would.
+func (f *Fake) SetVoiceState(guildID, userID, channelID string) {
+	f.mu.Lock()
+
+	g := f.guild(guildID)
+	var before *discordgo.VoiceState
+	for i, vs := range g.VoiceStates {
+		if vs.UserID == userID {
+			before = vs
+			g.VoiceStates = append(g.VoiceStates[:i], g.VoiceStates[i+1:]...)
+			break
+		}
+	}
+	update := &discordgo.VoiceStateUpdate{
+		VoiceState:   &discordgo.VoiceState{GuildID: guildID, UserID: userID, ChannelID: channelID},
+		BeforeUpdate: before,
+	}
+	if channelID != "" {
+		g.VoiceStates = append(g.VoiceStates, update.VoiceState)
+	}
+	handlers := append([]*fakeHandler(nil), f.handlers...)
+	f.mu.Unlock()
+
+	for _, h := range handlers {
+		if h.voiceState != nil {
+			h.voiceState(nil, update)
+		}
+	}
+}
+
+// AddMember registers member in guildID, creating the guild if needed.
+func (f *Fake) AddMember(guildID string, member *discordgo.Member) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	member.GuildID = guildID
+	f.guild(guildID).Members = append(f.guild(guildID).Members, member)
+}
+
+// SetPermissions sets the permissions UserChannelPermissions reports for userID.
+func (f *Fake) SetPermissions(userID string, perms int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.permissions[userID] = perms
+}
+
+func (f *Fake) guild(guildID string) *discordgo.Guild {
+	g, ok := f.guilds[guildID]
+	if !ok {
+		g = &discordgo.Guild{ID: guildID}
+		f.guilds[guildID] = g
+	}
+	return g
+}
+
+// Messages returns the content of every message sent to channelID, in order.
+func (f *Fake) Messages(channelID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var out []string
+	for _, m := range f.messages {
+		if m.ChannelID == channelID {
+			out = append(out, m.Content)
+		}
+	}
+	return out
+}
+
+// Message returns the message with id as it currently stands.
+func (f *Fake) Message(id string) (SentMessage, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range f.messages {
+		if m.ID == id {
+			return *m, true
+		}
+	}
+	return SentMessage{}, false
+}
+
+// Responses returns every interaction response sent so far.
+func (f *Fake) Responses() []InteractionResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]InteractionResponse(nil), f.responses...)
+}
+
+// Joins returns every voice join made so far.
+func (f *Fake) Joins() []VoiceJoin {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]VoiceJoin(nil), f.joins...)
+}
+
+// Dispatch delivers m to handlers registered with AddHandler.
+func (f *Fake) Dispatch(m *discordgo.MessageCreate) {
+	f.mu.Lock()
+	handlers := append([]*fakeHandler(nil), f.handlers...)
+	f.mu.Unlock()
+
+	for _, h := range handlers {
+		if h.message != nil {
+			h.message(nil, m)
+		}
+	}
+}
+
+// DispatchInteraction delivers i to interaction handlers registered with
+// AddHandler.
+func (f *Fake) DispatchInteraction(i *discordgo.InteractionCreate) {
+	f.mu.Lock()
+	handlers := append([]*fakeHandler(nil), f.handlers...)
+	f.mu.Unlock()
+
+	for _, h := range handlers {
+		if h.interaction != nil {
+			h.interaction(nil, i)
+		}
+	}
+}
+
+func (f *Fake) ChannelMessageSend(channelID, content string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	m := &SentMessage{ID: strconv.Itoa(f.nextID), ChannelID: channelID, Content: content}
+	f.messages = append(f.messages, m)
+	return &discordgo.Message{ID: m.ID, ChannelID: channelID, Content: content}, nil
+}
+
+func (f *Fake) ChannelMessageEdit(channelID, messageID, content string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range f.messages {
+		if m.ID == messageID && m.ChannelID == channelID {
+			m.Content = content
+			m.Edited = true
+			return &discordgo.Message{ID: m.ID, ChannelID: channelID, Content: content}, nil
+		}
+	}
+	return nil, errors.New("unknown message")
+}
+
+func (f *Fake) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	m := &SentMessage{
+		ID:         strconv.Itoa(f.nextID),
+		ChannelID:  channelID,
+		Content:    data.Content,
+		Embeds:     data.Embeds,
+		Components: data.Components,
+	}
+	for _, file := range data.Files {
+		content, err := io.ReadAll(file.Reader)
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, FakeFile{Name: file.Name, Content: content})
+	}
+	f.messages = append(f.messages, m)
+	return &discordgo.Message{ID: m.ID, ChannelID: channelID, Content: m.Content, Embeds: m.Embeds, Components: m.Components}, nil
+}
+
+func (f *Fake) ChannelMessageEditComplex(edit *discordgo.MessageEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range f.messages {
+		if m.ID != edit.ID || m.ChannelID != edit.Channel {
+			continue
+		}
+		if edit.Content != nil {
+			m.Content = *edit.Content
+		}
+		if edit.Embeds != nil {
+			m.Embeds = *edit.Embeds
+		}
+		if edit.Components != nil {
+			m.Components = *edit.Components
+		}
+		m.Edited = true
+		return &discordgo.Message{ID: m.ID, ChannelID: m.ChannelID, Content: m.Content, Embeds: m.Embeds, Components: m.Components}, nil
+	}
+	return nil, errors.New("unknown message")
+}
+
+// InteractionRespond records resp and, for message updates, applies it to
+// the message the interaction came from.
+func (f *Fake) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses = append(f.responses, InteractionResponse{InteractionID: interaction.ID, Response: resp})
+	if resp.Type != discordgo.InteractionResponseUpdateMessage || interaction.Message == nil || resp.Data == nil {
+		return nil
+	}
+	for _, m := range f.messages {
+		if m.ID == interaction.Message.ID {
+			m.Content = resp.Data.Content
+			m.Embeds = resp.Data.Embeds
+			m.Components = resp.Data.Components
+			m.Edited = true
+		}
+	}
+	return nil
+}
+
+func (f *Fake) BotUserID() string {
+	return f.botID
+}
+
+func (f *Fake) StateGuild(guildID string) (*discordgo.Guild, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g, ok := f.guilds[guildID]
+	if !ok {
+		return nil, discordgo.ErrStateNotFound
+	}
+	// A copy, so callers can range over it while voice states change.
+	c := *g
+	c.Channels = append([]*discordgo.Channel(nil), g.Channels...)
+	c.VoiceStates = append([]*discordgo.VoiceState(nil), g.VoiceStates...)
+	c.Members = append([]*discordgo.Member(nil), g.Members...)
+	return &c, nil
+}
+
+func (f *Fake) StateChannel(channelID string) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.channels[channelID]
+	if !ok {
+		return nil, discordgo.ErrStateNotFound
+	}
+	return c, nil
+}
+
+func (f *Fake) StateMember(guildID, userID string) (*discordgo.Member, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if g, ok := f.guilds[guildID]; ok {
+		for _, m := range g.Members {
+			if m.User != nil && m.User.ID == userID {
+				return m, nil
+			}
+		}
+	}
+	return nil, discordgo.ErrStateNotFound
+}
+
+func (f *Fake) UserChannelPermissions(userID, _ string, _ ...discordgo.RequestOption) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.permissions[userID], nil
+}
+
+func (f *Fake) JoinVoice(guildID, channelID string) (audio.AudioSink, error) {
+	f.mu.Lock()
+	f.joins = append(f.joins, VoiceJoin{GuildID: guildID, ChannelID: channelID})
+	newSink := f.NewSink
+	f.mu.Unlock()
+
+	return newSink(guildID, channelID)
+}
+
+func (f *Fake) AddHandler(handler interface{}) func() {
+	h := &fakeHandler{}
+	switch fn := handler.(type) {
+	case func(*discordgo.Session, *discordgo.MessageCreate):
+		h.message = fn
+	case func(*discordgo.Session, *discordgo.InteractionCreate):
+		h.interaction = fn
+	case func(*discordgo.Session, *discordgo.VoiceStateUpdate):
+		h.voiceState = fn
+	default:
+		return func() {}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.handlers = append(f.handlers, h)
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, other := range f.handlers {
+			if other == h {
+				f.handlers = append(f.handlers[:i], f.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
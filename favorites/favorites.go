@@ -0,0 +1,98 @@
+package favorites
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/audio"
+	"musicbot/store"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAlreadyLiked = errors.New("track is already liked")
+	ErrNotFound     = errors.New("track is not liked")
+)
+
+// Favorite is a track a user liked, with the metadata it had when it
+// played so it can be queued again without resolving it.
+type Favorite struct {
+	Track   *audio.Track `json:"track"`
+	LikedAt time.Time    `json:"liked_at"`
+}
+
+// Manager keeps every user's favorites. They are global, not per guild.
+type Manager struct {
+	favorites map[string][]*Favorite // userID → favorites, oldest first
+	store     *store.Store
+	sync.Mutex
+}
+
+const storeKey = "favorites"
+
+// NewManager loads every user's favorites from st.
+func NewManager(st *store.Store) (*Manager, error) {
+	m := &Manager{
+		favorites: make(map[string][]*Favorite),
+		store:     st,
+	}
+	if st != nil {
+		if _, err := st.Load(storeKey, &m.favorites); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// save persists every user's favorites. Callers must hold the lock.
+func (m *Manager) save() {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Save(storeKey, m.favorites); err != nil {
+		fmt.Println("Failed to save favorites:", err)
+	}
+}
+
+// Like adds track to userID's favorites.
+func (m *Manager) Like(userID string, track *audio.Track) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, f := range m.favorites[userID] {
+		if f.Track.Key() == track.Key() {
+			return ErrAlreadyLiked
+		}
+	}
+	m.favorites[userID] = append(m.favorites[userID], &Favorite{Track: track.Clean(), LikedAt: time.Now()})
+	m.save()
+	return nil
+}
+
+// Unlike removes track from userID's favorites and returns the removed one.
+func (m *Manager) Unlike(userID string, track *audio.Track) (*Favorite, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	for i, f := range m.favorites[userID] {
+		if f.Track.Key() == track.Key() {
+			m.favorites[userID] = append(m.favorites[userID][:i], m.favorites[userID][i+1:]...)
+			m.save()
+			return f, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns userID's favorites, most recently liked first.
+func (m *Manager) List(userID string) []*Favorite {
+	m.Lock()
+	defer m.Unlock()
+
+	favorites := m.favorites[userID]
+	out := make([]*Favorite, len(favorites))
+	for i, f := range favorites {
+		out[len(favorites)-1-i] = &Favorite{Track: f.Track.Clean(), LikedAt: f.LikedAt}
+	}
+	return out
+}
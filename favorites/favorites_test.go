@@ -0,0 +1,92 @@
+package favorites
+
+import (
+	"musicbot/audio"
+	"musicbot/store"
+	"testing"
+	"time"
+)
+
+func titles(favorites []*Favorite) []string {
+	var titles []string
+	for _, f := range favorites {
+		titles = append(titles, f.Track.Title)
+	}
+	return titles
+}
+
+func TestLikeAndUnlike(t *testing.T) {
+	m, err := NewManager(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playing := &audio.Track{
+		URL:         "https://www.youtube.com/watch?v=a",
+		Title:       "A",
+		Duration:    "3:00",
+		RequesterID: "bob",
+		EnqueuedAt:  time.Now(),
+		Offset:      time.Minute,
+		Autoplay:    true,
+	}
+	if err := m.Like("alice", playing); err != nil {
+		t.Fatal(err)
+	}
+	// Another link to the same video is the same track.
+	if err := m.Like("alice", &audio.Track{URL: "https://youtu.be/a"}); err != ErrAlreadyLiked {
+		t.Errorf("liking it again: got %v, want ErrAlreadyLiked", err)
+	}
+	m.Like("alice", &audio.Track{URL: "https://youtu.be/b", Title: "B"})
+	m.Like("bob", &audio.Track{URL: "https://youtu.be/c", Title: "C"})
+
+	likes := m.List("alice")
+	if got := titles(likes); len(got) != 2 || got[0] != "B" || got[1] != "A" {
+		t.Fatalf("alice likes %v, want B then A", got)
+	}
+	want := audio.Track{URL: playing.URL, Title: "A", Duration: "3:00"}
+	if *likes[1].Track != want {
+		t.Errorf("saved %+v, want only %+v", likes[1].Track, want)
+	}
+	// What List returns is a copy.
+	likes[0].Track.Title = "changed"
+	if m.List("alice")[0].Track.Title != "B" {
+		t.Error("changing a listed favorite changed the saved one")
+	}
+
+	removed, err := m.Unlike("alice", &audio.Track{URL: "https://youtu.be/a"})
+	if err != nil || removed.Track.Title != "A" {
+		t.Errorf("unliking A: got %+v, %v", removed, err)
+	}
+	if _, err := m.Unlike("alice", &audio.Track{URL: "https://youtu.be/c"}); err != ErrNotFound {
+		t.Errorf("unliking bob's track: got %v, want ErrNotFound", err)
+	}
+	if got := titles(m.List("alice")); len(got) != 1 || got[0] != "B" {
+		t.Errorf("alice likes %v after unliking A, want B", got)
+	}
+}
+
+func TestFavoritesPersist(t *testing.T) {
+	dir := t.TempDir()
+	st, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Like("alice", &audio.Track{URL: "https://youtu.be/a", Title: "A"})
+	m.Like("alice", &audio.Track{URL: "https://youtu.be/b", Title: "B"})
+	m.Unlike("alice", &audio.Track{URL: "https://youtu.be/a"})
+
+	st, err = store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err = NewManager(st); err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(m.List("alice")); len(got) != 1 || got[0] != "B" {
+		t.Errorf("reloaded likes %v, want B", got)
+	}
+}
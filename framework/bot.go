@@ -2,8 +2,9 @@ package framework
 
 import (
 	"fmt"
-	"musicbot/audio"
-	"musicbot/vc"
+	commands "musicbot/cmd"
+	"musicbot/discord"
+	"musicbot/store"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,10 +12,27 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
-var voiceManager *vc.VoiceManager
 var Session *discordgo.Session
-var queueManager *audio.QueueManager
-var audioSessions *audio.AudioSessionManager
+var client discord.Client
+var services *commands.Services
+
+// initServices creates the per-guild state shared by every command.
+func initServices(c discord.Client, st *store.Store) error {
+	var err error
+	client = c
+	services, err = commands.NewServices(st)
+	if err != nil {
+		return err
+	}
+
+	subscribeAnnouncements()
+	subscribeLogging()
+	subscribePersistence()
+	subscribeHistory()
+	subscribeSkipVotes()
+	subscribeStats()
+	return nil
+}
 
 func InitBot() {
 
@@ -25,20 +43,41 @@ func InitBot() {
 		panic(err)
 	}
 
-	voiceManager = vc.NewVoiceManager()
-	queueManager = audio.NewQueueManager()
-	audioSessions = audio.NewAudioSessionManager()
+	dataDir := os.Getenv("MUSICBOT_DATA_DIR")
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	st, err := store.Open(dataDir)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := initServices(discord.NewSession(Session), st); err != nil {
+		panic(err)
+	}
 
-	Session.AddHandler(onMessageCreate)
+	Session.AddHandler(func(_ *discordgo.Session, m *discordgo.MessageCreate) {
+		onMessageCreate(client, m)
+	})
+	Session.AddHandler(func(_ *discordgo.Session, i *discordgo.InteractionCreate) {
+		onInteractionCreate(client, i)
+	})
+	Session.AddHandler(func(_ *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+		services.VoiceStateChanged(client, v)
+	})
 	err = Session.Open()
 	if err != nil {
 		panic(err)
 	}
 
+	restoreSavedQueues()
+	startScheduler()
+
 	fmt.Println("Bot is running...")
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 
+	saveAllQueues()
 	Session.Close()
 }
@@ -0,0 +1,77 @@
+package framework
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"time"
+)
+
+// subscribeAnnouncements posts playback changes in the channel that started
+// playback and leaves voice once a player runs out of tracks. One
+// subscription keeps the announcements in order.
+func subscribeAnnouncements() {
+	services.Bus.SubscribeAll(func(ev audio.Event) {
+		channelID := services.Players.Get(ev.Guild()).TextChannel()
+
+		switch e := ev.(type) {
+		case audio.TrackStarted:
+			track := e.Track
+			msg := fmt.Sprintf("🎶 Now Playing: %s\n⏱️ Duration: %s\n👤 Uploader: %s", track.Title, track.Duration, track.Uploader)
+			if track.Autoplay {
+				msg += "\n📻 Autoplayed"
+			}
+			client.ChannelMessageSend(channelID, msg)
+
+		case audio.TrackEnded:
+			if e.Reason == audio.EndError {
+				client.ChannelMessageSend(channelID, "⚠️ Error playing track: "+e.Err.Error())
+			}
+
+		case audio.SleepWarning:
+			client.ChannelMessageSend(channelID, fmt.Sprintf("😴 Sleep timer: stopping in %s.", e.Left.Round(time.Second)))
+
+		case audio.SleepExpired:
+			client.ChannelMessageSend(channelID, "😴 Sleep timer is up. Good night!")
+			services.CancelExpansions(e.GuildID)
+			// The player may already have been idle, e.g. after >join.
+			services.Encoders.Release(e.GuildID)
+			_ = services.VoiceManager.Leave(e.GuildID)
+
+		case audio.StateChanged:
+			if e.To == audio.StateIdle && services.LeaveIdle(e.GuildID) {
+				client.ChannelMessageSend(channelID, "👋 Finished playback. Left the voice channel.")
+			}
+		}
+	})
+}
+
+func subscribeLogging() {
+	services.Bus.SubscribeAll(func(ev audio.Event) {
+		switch e := ev.(type) {
+		case audio.TrackStarted:
+			fmt.Printf("[%s] track started: %s\n", e.GuildID, e.Track.URL)
+		case audio.TrackEnded:
+			fmt.Printf("[%s] track ended (%s): %s\n", e.GuildID, e.Reason, e.Track.URL)
+		case audio.StateChanged:
+			fmt.Printf("[%s] player %s → %s\n", e.GuildID, e.From, e.To)
+		case audio.VoiceJoined:
+			fmt.Printf("[%s] joined voice channel %s\n", e.GuildID, e.ChannelID)
+		case audio.VoiceLeft:
+			fmt.Printf("[%s] left voice\n", e.GuildID)
+		}
+	})
+}
+
+func subscribeHistory() {
+	audio.Subscribe(services.Bus, services.RecordHistory)
+}
+
+func subscribeSkipVotes() {
+	audio.Subscribe(services.Bus, services.ResetSkipVotes)
+}
+
+func subscribeStats() {
+	services.Bus.SubscribeAll(func(ev audio.Event) {
+		services.RecordStats(client, ev)
+	})
+}
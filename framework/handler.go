@@ -2,18 +2,65 @@ package framework
 
 import (
 	commands "musicbot/cmd"
+	"musicbot/discord"
 	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-func onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
-	if m.Author.ID == s.State.User.ID {
+// helpSections are the messages >help sends. Discord refuses messages
+// over 2000 characters, so each section stays under that.
+var helpSections = []string{
+	"**Playback**\n" +
+		"`>ping` - Responds with Pong>\n" +
+		"`>help` - Displays this help message\n" +
+		"`>join`, `>leave` - Voice connection\n" +
+		"`>play <url>` - Play a YouTube video or playlist\n" +
+		"`>play <playlist url> [shuffle] [from-to]` - Play part of a playlist, or shuffled\n" +
+		"`>playnext <url|query>` - Queue at the front\n" +
+		"`>playnow <url|query> [--keep]` - Play straight away (`--keep` resumes the current track afterwards)\n" +
+		"`>pause`, `>resume`, `>skip`, `>stop`\n" +
+		"`>loop one|all|off|toggle` - Set loop mode\n" +
+		"`>autoplay on|off` - Keep playing related tracks when the queue runs out\n" +
+		"`>nowplaying`, `>search <query>`\n" +
+		"`>sleep <duration> [playtime]`, `>sleep end|cancel|status` - Stop and leave later",
+
+	"**Queue**\n" +
+		"`>queue` - Show queue\n" +
+		"`>queue clear|shuffle` - Manage queue\n" +
+		"`>queue shuffle smart` - Shuffle keeping uploaders and requesters apart\n" +
+		"`>queue shuffle on|off` - Insert new tracks at random positions\n" +
+		"`>queue insert <index> <url>`\n" +
+		"`>queue remove <index>|<from>-<to>|mine|user @name`\n" +
+		"`>queue dedupe`, `>queue find <text>`, `>skipto <position|text>`\n" +
+		"`>queue export m3u|xspf|json`, `>queue import [urls]` (or attach a file)\n" +
+		"`>queue move <from> <to>`\n" +
+		"`>undo`, `>redo` - Undo or redo the last change to the queue\n" +
+		"`>restore [discard]` - Resume the queue saved before a restart",
+
+	"**Your music**\n" +
+		"`>history [page]`, `>previous` - Recently played tracks\n" +
+		"`>stats [me]`, `>top tracks [week|month|all]`, `>top requesters` - Listening stats\n" +
+		"`>like`, `>unlike [number]`, `>likes [page]`, `>likes play [shuffle]` - Your favorite tracks\n" +
+		"`>playlist save|load|show|delete <name>`, `>playlist add <name> <url>`\n" +
+		"`>playlist share <name> on|off`, `>playlist list` - Your saved playlists",
+
+	"**Server (admin)**\n" +
+		"`>quality [bitrate|complexity|fec|loss|reset]` - Audio quality\n" +
+		"`>limit [queue|peruser|playlist|duration <value>]` - Queue limits\n" +
+		"`>limit duplicates allow|reject|reject-queued` - `reject` also refuses recently played tracks\n" +
+		"`>schedule add <cron> <voice channel> <playlist|url>`, `>schedule list|remove <id>` - Play at set times\n" +
+		"`>settings [autorestore|fairqueue on|off]`, `>settings djrole <@role>|none`, `>settings autoplaywindow <tracks>`\n" +
+		"`>settings voteskip on|off`, `>settings voteskippercent <1-100>`, `>settings playlistcap <tracks>`, `>settings timezone <zone>` - Server settings",
+}
+
+func onMessageCreate(s discord.Client, m *discordgo.MessageCreate) {
+	if m.Author.ID == s.BotUserID() {
 		return
 	}
 
-	cmd := commands.NewBotCommand(s, m, voiceManager, queueManager, audioSessions)
+	cmd := commands.NewBotCommand(s, m, services)
 	args := strings.Fields(m.Content)
 
 	if len(args) == 0 || !strings.HasPrefix(args[0], ">") {
@@ -25,20 +72,9 @@ func onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		s.ChannelMessageSend(m.ChannelID, "Pong!")
 
 	case ">help":
-		s.ChannelMessageSend(m.ChannelID,
-			"Available commands:\n"+
-				"`>ping` - Responds with Pong>\n"+
-				"`>help` - Displays this help message\n"+
-				"`>join`, `>leave` - Voice connection\n"+
-				"`>play <url>` - Play a YouTube video or playlist\n"+
-				"`>pause`, `>resume`, `>skip`, `>stop`\n"+
-				"`>queue` - Show queue\n"+
-				"`>queue clear|shuffle` - Manage queue\n"+
-				"`>queue insert <index> <url>`\n"+
-				"`>queue remove <index>`\n"+
-				"`>queue move <from> <to>`\n"+
-				"`>loop one|all|off|toggle` - Set loop mode\n"+
-				"`>nowplaying`, `>search <query>`")
+		for _, section := range helpSections {
+			s.ChannelMessageSend(m.ChannelID, section)
+		}
 
 	case ">info":
 		s.ChannelMessageSend(m.ChannelID, "🎵 This is a music bot written in Go using DiscordGo.\nSupports playback, queues, and loop modes.")
@@ -51,7 +87,27 @@ func onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 			s.ChannelMessageSend(m.ChannelID, "Usage: `>play <youtube_url>`")
 			return
 		}
-		cmd.Play(args[1])
+		cmd.Play(args[1], args[2:])
+
+	case ">playnext":
+		input := strings.TrimSpace(strings.Join(args[1:], " "))
+		if input == "" {
+			s.ChannelMessageSend(m.ChannelID, "Usage: `>playnext <url|query>`")
+			return
+		}
+		go cmd.PlayNext(input)
+
+	case ">playnow":
+		keep := len(args) > 1 && args[len(args)-1] == "--keep"
+		if keep {
+			args = args[:len(args)-1]
+		}
+		input := strings.TrimSpace(strings.Join(args[1:], " "))
+		if input == "" {
+			s.ChannelMessageSend(m.ChannelID, "Usage: `>playnow <url|query> [--keep]`")
+			return
+		}
+		go cmd.PlayNow(input, keep)
 
 	case ">leave":
 		cmd.Leave()
@@ -64,7 +120,34 @@ func onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		case len(args) == 2 && args[1] == "clear":
 			cmd.ClearQueue()
 		case len(args) == 2 && args[1] == "shuffle":
-			cmd.ShuffleQueue()
+			cmd.ShuffleQueue("")
+		case len(args) == 3 && args[1] == "shuffle":
+			cmd.ShuffleQueue(args[2])
+		case len(args) == 3 && args[1] == "remove" && args[2] == "mine":
+			cmd.RemoveByUser(m.Author.ID)
+		case len(args) == 4 && args[1] == "remove" && args[2] == "user":
+			userID := strings.TrimSuffix(strings.TrimLeft(args[3], "<@!"), ">")
+			if len(m.Mentions) > 0 {
+				userID = m.Mentions[0].ID
+			}
+			cmd.RemoveByUser(userID)
+		case len(args) == 3 && args[1] == "remove" && strings.Contains(args[2], "-"):
+			from, to, ok := strings.Cut(args[2], "-")
+			start, err1 := strconv.Atoi(from)
+			end, err2 := strconv.Atoi(to)
+			if !ok || err1 != nil || err2 != nil {
+				s.ChannelMessageSend(m.ChannelID, "⚠️ Invalid range. Use e.g. `>queue remove 3-10`.")
+				return
+			}
+			cmd.RemoveRange(start, end)
+		case len(args) == 3 && args[1] == "export":
+			cmd.ExportQueue(args[2])
+		case len(args) >= 2 && args[1] == "import":
+			cmd.ImportQueue(args[2:])
+		case len(args) == 2 && args[1] == "dedupe":
+			cmd.Dedupe()
+		case len(args) >= 3 && args[1] == "find":
+			cmd.FindInQueue(strings.Join(args[2:], " "))
 		case len(args) == 3 && args[1] == "remove":
 			index, err := strconv.Atoi(args[2])
 			if err != nil {
@@ -92,6 +175,13 @@ func onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 			cmd.Queue()
 		}
 
+	case ">skipto":
+		if len(args) < 2 {
+			s.ChannelMessageSend(m.ChannelID, "Usage: `>skipto <position|text>`")
+			return
+		}
+		cmd.SkipTo(strings.Join(args[1:], " "))
+
 	case ">stop":
 		cmd.Stop()
 
@@ -118,6 +208,120 @@ func onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 			s.ChannelMessageSend(m.ChannelID, "Invalid loop mode. Use: `one`, `all`, `off`, or `toggle`.")
 		}
 
+	case ">autoplay":
+		if len(args) < 2 {
+			s.ChannelMessageSend(m.ChannelID, "Usage: `>autoplay on|off`")
+			return
+		}
+		cmd.Autoplay(args[1])
+
+	case ">limit", ">limits":
+		cmd.Limit(args[1:])
+
+	case ">quality":
+		cmd.Quality(args[1:])
+
+	case ">history":
+		page := 1
+		if len(args) >= 2 {
+			p, err := strconv.Atoi(args[1])
+			if err != nil {
+				s.ChannelMessageSend(m.ChannelID, "⚠️ Invalid page.")
+				return
+			}
+			page = p
+		}
+		cmd.ShowHistory(page)
+
+	case ">stats":
+		cmd.ShowStats(len(args) >= 2 && args[1] == "me")
+
+	case ">top":
+		switch {
+		case len(args) >= 2 && args[1] == "tracks":
+			period := "all"
+			if len(args) >= 3 {
+				period = args[2]
+			}
+			cmd.TopTracks(period)
+		case len(args) >= 2 && args[1] == "requesters":
+			cmd.TopRequesters()
+		default:
+			s.ChannelMessageSend(m.ChannelID, "Usage: `>top tracks [week|month|all]` or `>top requesters`")
+		}
+
+	case ">like":
+		cmd.Like()
+
+	case ">unlike":
+		n := 0
+		if len(args) >= 2 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil {
+				s.ChannelMessageSend(m.ChannelID, "Usage: `>unlike [number]`")
+				return
+			}
+		}
+		cmd.Unlike(n)
+
+	case ">likes":
+		if len(args) >= 2 && args[1] == "play" {
+			cmd.PlayLikes(len(args) >= 3 && args[2] == "shuffle")
+			return
+		}
+		page := 1
+		if len(args) >= 2 {
+			p, err := strconv.Atoi(args[1])
+			if err != nil {
+				s.ChannelMessageSend(m.ChannelID, "⚠️ Invalid page.")
+				return
+			}
+			page = p
+		}
+		cmd.ShowLikes(page)
+
+	case ">previous", ">back":
+		cmd.Previous()
+
+	case ">playlist":
+		usage := "Usage: `>playlist save|load|show|delete <name>`, `>playlist add <name> <url>`, `>playlist share <name> on|off` or `>playlist list`"
+		switch {
+		case len(args) == 2 && args[1] == "list":
+			cmd.PlaylistList()
+		case len(args) == 3 && args[1] == "save":
+			cmd.PlaylistSave(args[2])
+		case len(args) == 3 && args[1] == "load":
+			cmd.PlaylistLoad(args[2])
+		case len(args) == 3 && args[1] == "show":
+			cmd.PlaylistShow(args[2])
+		case len(args) == 3 && args[1] == "delete":
+			cmd.PlaylistDelete(args[2])
+		case len(args) == 4 && args[1] == "add":
+			cmd.PlaylistAdd(args[2], args[3])
+		case len(args) == 4 && args[1] == "share":
+			cmd.PlaylistShare(args[2], args[3])
+		default:
+			s.ChannelMessageSend(m.ChannelID, usage)
+		}
+
+	case ">sleep":
+		cmd.Sleep(args[1:])
+
+	case ">undo":
+		cmd.Undo()
+
+	case ">redo":
+		cmd.Redo()
+
+	case ">restore":
+		cmd.Restore(len(args) == 2 && args[1] == "discard")
+
+	case ">schedule":
+		cmd.Schedule(args[1:])
+
+	case ">settings":
+		cmd.GuildSettings(args[1:])
+
 	case ">search":
 		query := strings.TrimSpace(strings.Join(args[1:], " "))
 		if query == "" {
@@ -130,3 +334,14 @@ func onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		s.ChannelMessageSend(m.ChannelID, "Unknown command. Type `>help` for available commands.")
 	}
 }
+
+func onInteractionCreate(s discord.Client, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	switch id := i.MessageComponentData().CustomID; {
+	case strings.HasPrefix(id, commands.QueueButtonPrefix):
+		services.QueueButton(s, i)
+	}
+}
@@ -0,0 +1,726 @@
+package framework
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/discord"
+	"musicbot/store"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const adminID = "admin"
+
+// fakeYTDLP answers metadata lookups with a title made from the URL and
+// streams nothing. A playlist ?list=name lists its first entry, name1,
+// then waits for the file playlists/name and lists what it holds.
+const fakeYTDLP = `#!/bin/sh
+for arg; do url=$arg; done
+case "$*" in
+*--flat-playlist*)
+	list=${url##*list=}
+	echo "https://youtu.be/${list}1|3:00|Uploader|Title ${list}1"
+	while [ ! -e "$FAKE_PLAYLISTS/$list" ]; do sleep 0.01; done
+	cat "$FAKE_PLAYLISTS/$list" ;;
+*--print*) echo "Title ${url##*/}|3:00|Uploader ${url##*/}" ;;
+esac
+`
+
+// fakeFFmpeg plays for as long as it is left running.
+const fakeFFmpeg = `#!/bin/sh
+exec sleep 60
+`
+
+var (
+	fake      *discord.Fake
+	playlists string // where fakeYTDLP waits for the rest of a playlist
+	scenarios atomic.Int64
+)
+
+// TestMain starts the bot once against a discord.Fake, with stand-ins for
+// yt-dlp and ffmpeg on the PATH. Each scenario gets a guild of its own.
+func TestMain(m *testing.M) {
+	os.Exit(func() int {
+		if runtime.GOOS == "windows" {
+			fmt.Println("skipping: the yt-dlp and ffmpeg stand-ins are shell scripts")
+			return 0
+		}
+		dir, err := os.MkdirTemp("", "musicbot-test")
+		if err != nil {
+			panic(err)
+		}
+		defer os.RemoveAll(dir)
+
+		bin := filepath.Join(dir, "bin")
+		os.Mkdir(bin, 0o755)
+		for name, script := range map[string]string{"yt-dlp": fakeYTDLP, "ffmpeg": fakeFFmpeg} {
+			if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+				panic(err)
+			}
+		}
+		os.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+		playlists = filepath.Join(dir, "playlists")
+		os.Mkdir(playlists, 0o755)
+		os.Setenv("FAKE_PLAYLISTS", playlists)
+
+		st, err := store.Open(filepath.Join(dir, "data"))
+		if err != nil {
+			panic(err)
+		}
+		fake = discord.NewFake("bot")
+		fake.SetPermissions(adminID, discordgo.PermissionAdministrator)
+		if err := initServices(fake, st); err != nil {
+			panic(err)
+		}
+		return m.Run()
+	}())
+}
+
+// scenario drives onMessageCreate in a guild of its own.
+type scenario struct {
+	t       *testing.T
+	guildID string
+	textID  string
+	voiceID string
+	nextID  atomic.Int64
+}
+
+func newScenario(t *testing.T) *scenario {
+	n := scenarios.Add(1)
+	s := &scenario{
+		t:       t,
+		guildID: fmt.Sprint("guild", n),
+		textID:  fmt.Sprint("text", n),
+		voiceID: fmt.Sprint("voice", n),
+	}
+	fake.AddChannel(s.guildID, &discordgo.Channel{ID: s.textID, Name: "music", Type: discordgo.ChannelTypeGuildText})
+	fake.AddChannel(s.guildID, &discordgo.Channel{ID: s.voiceID, Name: "focus room", Type: discordgo.ChannelTypeGuildVoice, Bitrate: 64000})
+
+	t.Cleanup(func() {
+		services.CancelExpansions(s.guildID)
+		player := services.Players.Get(s.guildID)
+		player.Stop()
+		player.Detach()
+	})
+	return s
+}
+
+// send delivers content as a message from userID in the text channel.
+func (s *scenario) send(userID, content string) {
+	s.t.Helper()
+	m := &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        fmt.Sprint("m", s.nextID.Add(1)),
+		GuildID:   s.guildID,
+		ChannelID: s.textID,
+		Content:   content,
+		Author:    &discordgo.User{ID: userID, Username: userID},
+	}}
+	onMessageCreate(fake, m)
+}
+
+// waitFor fails the test unless cond holds within a few seconds.
+func (s *scenario) waitFor(what string, cond func() bool) {
+	s.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			s.t.Fatalf("timed out waiting for %s; messages so far:\n%s", what, strings.Join(fake.Messages(s.textID), "\n---\n"))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// expect waits for a message containing text in the text channel.
+func (s *scenario) expect(text string) {
+	s.t.Helper()
+	s.waitFor(fmt.Sprintf("a message containing %q", text), func() bool {
+		for _, msg := range fake.Messages(s.textID) {
+			if strings.Contains(msg, text) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (s *scenario) last() string {
+	msgs := fake.Messages(s.textID)
+	if len(msgs) == 0 {
+		return ""
+	}
+	return msgs[len(msgs)-1]
+}
+
+// joins returns the voice joins made in the scenario's guild.
+func (s *scenario) joins() []discord.VoiceJoin {
+	var joins []discord.VoiceJoin
+	for _, j := range fake.Joins() {
+		if j.GuildID == s.guildID {
+			joins = append(joins, j)
+		}
+	}
+	return joins
+}
+
+func (s *scenario) queue() *audio.Queue {
+	return services.QueueManager.Get(s.guildID)
+}
+
+func (s *scenario) queued() []string {
+	var urls []string
+	for _, t := range s.queue().List() {
+		urls = append(urls, t.URL)
+	}
+	return urls
+}
+
+func (s *scenario) expectQueued(urls ...string) {
+	s.t.Helper()
+	if got := s.queued(); strings.Join(got, " ") != strings.Join(urls, " ") {
+		s.t.Errorf("queue = %v, want %v", got, urls)
+	}
+}
+
+func (s *scenario) expectState(state audio.PlayerState) {
+	s.t.Helper()
+	s.waitFor("the player to be "+state.String(), func() bool {
+		return services.Players.Get(s.guildID).State() == state
+	})
+}
+
+func (s *scenario) expectPlaying(url string) {
+	s.t.Helper()
+	s.waitFor(url+" to play", func() bool {
+		current := s.queue().Current()
+		return services.Players.Get(s.guildID).State() == audio.StatePlaying && current != nil && current.URL == url
+	})
+}
+
+func TestPing(t *testing.T) {
+	s := newScenario(t)
+	s.send("alice", ">ping")
+	if s.last() != "Pong!" {
+		t.Errorf("got %q, want Pong!", s.last())
+	}
+}
+
+func TestHelpFitsInMessages(t *testing.T) {
+	s := newScenario(t)
+	s.send("alice", ">help")
+	msgs := fake.Messages(s.textID)
+	if len(msgs) != len(helpSections) {
+		t.Fatalf("sent %d messages, want %d", len(msgs), len(helpSections))
+	}
+	for _, msg := range msgs {
+		// Discord's limit counts characters, not bytes.
+		if n := utf8.RuneCountInString(msg); n > 2000 {
+			t.Errorf("help message of %d characters, over Discord's 2000: %q", n, msg)
+		}
+	}
+	if help := strings.Join(msgs, "\n"); !strings.Contains(help, "`>like`") || !strings.Contains(help, "`>stats [me]`") {
+		t.Error("help leaves out favorites or stats")
+	}
+}
+
+func TestIgnoresBotsAndOtherMessages(t *testing.T) {
+	s := newScenario(t)
+	s.send("bot", ">ping")
+	s.send("alice", "hello there")
+	if msgs := fake.Messages(s.textID); len(msgs) != 0 {
+		t.Errorf("sent %q, want nothing", msgs)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	s := newScenario(t)
+	s.send("alice", ">dance")
+	s.expect("Unknown command")
+}
+
+func TestPlayNeedsVoice(t *testing.T) {
+	s := newScenario(t)
+	s.send("alice", ">play https://youtu.be/a")
+	s.expect("You must be in a voice channel")
+	if len(s.joins()) != 0 || len(s.queued()) != 0 {
+		t.Errorf("joined %v and queued %v without the author in voice", s.joins(), s.queued())
+	}
+}
+
+func TestPlayJoinsAndPlays(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	s.send("alice", ">play https://youtu.be/a")
+	s.expect("Added to queue")
+	s.expect("Now Playing: Title a")
+	s.expectPlaying("https://youtu.be/a")
+
+	if joins := s.joins(); len(joins) != 1 || joins[0].ChannelID != s.voiceID {
+		t.Errorf("joins = %v, want one join of %s", joins, s.voiceID)
+	}
+	current := s.queue().Current()
+	if current.RequesterID != "alice" || current.Uploader != "Uploader a" {
+		t.Errorf("current track = %+v, want alice's with resolved metadata", current)
+	}
+}
+
+func TestQueueSkipAndStop(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	s.send("alice", ">play https://youtu.be/a")
+	s.send("alice", ">play https://youtu.be/b")
+	s.send("alice", ">play https://youtu.be/c")
+	s.expectPlaying("https://youtu.be/a")
+	s.expectQueued("https://youtu.be/b", "https://youtu.be/c")
+
+	s.send("alice", ">skip")
+	s.expect("Skipped current track")
+	s.expect("Now Playing: Title b")
+	s.expectPlaying("https://youtu.be/b")
+	s.expectQueued("https://youtu.be/c")
+
+	s.send("alice", ">stop")
+	s.expect("Stopped playback and cleared the queue")
+	s.expect("Finished playback. Left the voice channel.")
+	s.expectState(audio.StateIdle)
+	s.expectQueued()
+	if _, ok := services.VoiceManager.Get(s.guildID); ok {
+		t.Error("still in voice after >stop")
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	s.send("alice", ">pause")
+	s.expect("Nothing is playing")
+
+	s.send("alice", ">play https://youtu.be/a")
+	s.expectPlaying("https://youtu.be/a")
+	s.send("alice", ">pause")
+	s.expect("Paused playback")
+	s.expectState(audio.StatePaused)
+	s.send("alice", ">pause")
+	s.expect("Already paused")
+	s.send("alice", ">resume")
+	s.expect("Resumed playback")
+	s.expectState(audio.StatePlaying)
+}
+
+func TestQueueEditsAndUndo(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		s.send("alice", ">play https://youtu.be/"+id)
+	}
+	s.expectPlaying("https://youtu.be/a")
+	s.expectQueued("https://youtu.be/b", "https://youtu.be/c", "https://youtu.be/d")
+
+	s.send("alice", ">queue move 3 1")
+	s.expect("Moved track from position 3 to 1")
+	s.expectQueued("https://youtu.be/d", "https://youtu.be/b", "https://youtu.be/c")
+
+	s.send("alice", ">queue remove 2")
+	s.expectQueued("https://youtu.be/d", "https://youtu.be/c")
+
+	s.send("alice", ">queue clear")
+	s.expect("Cleared the queue")
+	s.expectQueued()
+
+	s.send("alice", ">undo")
+	s.expect("Undid clear by <@alice>")
+	s.expectQueued("https://youtu.be/d", "https://youtu.be/c")
+
+	s.send("alice", ">redo")
+	s.expect("Redid clear")
+	s.expectQueued()
+}
+
+func TestLimits(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	s.send("alice", ">limit peruser 1")
+	s.expect("Only server admins can change limits")
+	s.send(adminID, ">limit peruser 1")
+	s.expect("Updated the peruser limit")
+
+	s.send("alice", ">play https://youtu.be/a")
+	s.expectPlaying("https://youtu.be/a")
+	s.send("alice", ">play https://youtu.be/b")
+	s.send("alice", ">play https://youtu.be/c")
+	s.expect("you can have at most 1 tracks queued")
+	s.expectQueued("https://youtu.be/b")
+
+	// Admins are exempt.
+	fake.SetVoiceState(s.guildID, adminID, s.voiceID)
+	s.send(adminID, ">play https://youtu.be/c")
+	s.send(adminID, ">play https://youtu.be/d")
+	s.expectQueued("https://youtu.be/b", "https://youtu.be/c", "https://youtu.be/d")
+
+	// reject also turns away what was just played.
+	s.send(adminID, ">limit duplicates reject")
+	s.expect("Updated the duplicates limit")
+	s.send(adminID, ">skip")
+	s.expectPlaying("https://youtu.be/b")
+	s.waitFor("a in history", func() bool { return services.History.Get(s.guildID).Len() == 1 })
+	s.send("alice", ">play https://youtu.be/a")
+	s.expect("tracks among the last 20 played can't be added again")
+	s.expectQueued("https://youtu.be/c", "https://youtu.be/d")
+}
+
+func TestLoop(t *testing.T) {
+	s := newScenario(t)
+	s.send("alice", ">loop all")
+	s.expect("Loop mode set to: all")
+	if mode := s.queue().GetLoopMode(); mode != audio.LoopAll {
+		t.Errorf("loop mode = %s, want all", mode)
+	}
+	s.send("alice", ">loop sideways")
+	s.expect("Invalid loop mode")
+}
+
+func TestPlaylistSaveUnderLoopAll(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	for _, id := range []string{"a", "b", "c"} {
+		s.send("alice", ">play https://youtu.be/"+id)
+	}
+	s.expectPlaying("https://youtu.be/a")
+	s.send("alice", ">loop all")
+	s.send("alice", ">skip")
+	s.expectPlaying("https://youtu.be/b")
+	s.expectQueued("https://youtu.be/c", "https://youtu.be/b")
+
+	// Loop-all has put b back at the end of the queue; it is saved once.
+	s.send("alice", ">playlist save mix")
+	s.expect("Saved 2 tracks to playlist **mix**")
+}
+
+func TestBulkRemovalSparesOthersTracks(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+	fake.SetVoiceState(s.guildID, "bob", s.voiceID)
+
+	s.send("alice", ">play https://youtu.be/a")
+	s.expectPlaying("https://youtu.be/a")
+	s.send("bob", ">play https://youtu.be/b")
+	s.send("alice", ">play https://youtu.be/c")
+	s.send("bob", ">play https://youtu.be/b")
+	s.send("alice", ">play https://youtu.be/c")
+
+	s.send("alice", ">queue remove user <@bob>")
+	s.expect("Only DJs can remove other people's tracks")
+	s.send("alice", ">queue dedupe")
+	s.expect("Removed 1 of your duplicate tracks")
+	s.expectQueued("https://youtu.be/b", "https://youtu.be/c", "https://youtu.be/b")
+	s.send("alice", ">queue remove 1-3")
+	s.expect("Removed 1 of your tracks from positions 1-3")
+	s.expectQueued("https://youtu.be/b", "https://youtu.be/b")
+
+	s.send(adminID, ">queue dedupe")
+	s.expect("Removed 1 duplicate tracks")
+	s.expectQueued("https://youtu.be/b")
+}
+
+func TestSkipToMatch(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		s.send("alice", ">play https://youtu.be/"+id)
+	}
+	s.expectPlaying("https://youtu.be/a")
+	s.send("alice", ">skipto youtu.be/c")
+	s.expect("Skipped to position 2")
+	s.expectPlaying("https://youtu.be/c")
+	s.expectQueued("https://youtu.be/d")
+
+	s.send("alice", ">skipto nothing like it")
+	s.expect("Nothing in the queue matches")
+}
+
+func TestUndoLeavesPlayedTracksOut(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		s.send("alice", ">play https://youtu.be/"+id)
+	}
+	s.expectPlaying("https://youtu.be/a")
+	s.send("alice", ">queue remove 3")
+	s.expectQueued("https://youtu.be/b", "https://youtu.be/c")
+	s.send("alice", ">skip")
+	s.expectPlaying("https://youtu.be/b")
+
+	// b has played since d was removed, so only d comes back.
+	s.send("alice", ">undo")
+	s.expect("Undid remove")
+	s.expectQueued("https://youtu.be/c", "https://youtu.be/d")
+}
+
+func TestUndoNeedsPermission(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	s.send("alice", ">play https://youtu.be/a")
+	s.send("alice", ">play https://youtu.be/b")
+	s.expectPlaying("https://youtu.be/a")
+	s.send("alice", ">queue clear")
+	s.expectQueued()
+
+	s.send("bob", ">undo")
+	s.expect("Only DJs and <@alice> can undo clear")
+	s.expectQueued()
+	s.send(adminID, ">undo")
+	s.expect("Undid clear by <@alice>")
+	s.expectQueued("https://youtu.be/b")
+
+	// Moving needs a DJ in fair mode, and so does undoing a move.
+	s.send("alice", ">play https://youtu.be/c")
+	s.send("alice", ">queue move 2 1")
+	s.expectQueued("https://youtu.be/c", "https://youtu.be/b")
+	s.send(adminID, ">settings fairqueue on")
+	s.send("alice", ">undo")
+	s.expect("Fair queue is on, so only DJs can undo move")
+	s.expectQueued("https://youtu.be/c", "https://youtu.be/b")
+}
+
+func TestUndoPlayNow(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	s.send("alice", ">play https://youtu.be/a")
+	s.send("alice", ">play https://youtu.be/b")
+	s.expectPlaying("https://youtu.be/a")
+	s.send("alice", ">playnow https://youtu.be/x --keep")
+	s.expectPlaying("https://youtu.be/x")
+	s.expectQueued("https://youtu.be/a", "https://youtu.be/b")
+
+	s.send("alice", ">undo")
+	s.expect("Undid play now")
+	s.expectQueued("https://youtu.be/b")
+}
+
+// finishPlaylist lets fakeYTDLP list the rest of playlist name: entries
+// name2, name3 and so on up to count.
+func (s *scenario) finishPlaylist(name string, count int) {
+	s.t.Helper()
+	var rest string
+	for i := 2; i <= count; i++ {
+		rest += fmt.Sprintf("https://youtu.be/%s%d|3:00|Uploader|Title %s%d\n", name, i, name, i)
+	}
+	if err := os.WriteFile(filepath.Join(playlists, name), []byte(rest), 0o644); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+func TestPlaylistOutlivesItsFirstTrack(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+	list := "outlive" + s.guildID
+
+	go s.send("alice", ">play https://www.youtube.com/playlist?list="+list)
+	s.expect("Loading the playlist, starting with Title " + list + "1")
+	s.expectPlaying("https://youtu.be/" + list + "1")
+
+	// The first track ends before the rest of the playlist is listed.
+	s.send("alice", ">skip")
+	s.expectState(audio.StateIdle)
+	if _, ok := services.VoiceManager.Get(s.guildID); !ok {
+		t.Fatal("left voice while the playlist was loading")
+	}
+
+	s.finishPlaylist(list, 3)
+	s.expect("Enqueued 3 tracks from the playlist.")
+	s.expectPlaying("https://youtu.be/" + list + "2")
+	s.expectQueued("https://youtu.be/" + list + "3")
+	for _, msg := range fake.Messages(s.textID) {
+		if strings.Contains(msg, "Finished playback") {
+			t.Errorf("got %q while the playlist was loading", msg)
+		}
+	}
+
+	// The whole playlist is one change to undo.
+	s.send("alice", ">undo")
+	s.expect("Undid playlist by <@alice>")
+	s.expectQueued()
+	s.send("alice", ">undo")
+	s.expect("Nothing to undo.")
+}
+
+func TestPlaylistLeavesWhenDoneIdle(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+	list := "idle" + s.guildID
+
+	go s.send("alice", ">play https://www.youtube.com/playlist?list="+list)
+	s.expectPlaying("https://youtu.be/" + list + "1")
+	s.send("alice", ">skip")
+	s.expectState(audio.StateIdle)
+
+	// Nothing more is listed, so the player stays idle and the bot leaves.
+	s.finishPlaylist(list, 1)
+	s.expect("Finished playback. Left the voice channel.")
+	if _, ok := services.VoiceManager.Get(s.guildID); ok {
+		t.Error("still in voice after the playlist ran out")
+	}
+}
+
+func TestPlaylistLimitMessage(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+	s.send(adminID, ">limit playlist 2")
+
+	// A playlist exactly as long as the limit wasn't cut short.
+	exact := "exact" + s.guildID
+	s.finishPlaylist(exact, 2)
+	s.send("alice", ">play https://www.youtube.com/playlist?list="+exact)
+	s.expect("Enqueued 2 tracks from the playlist.")
+	if last := s.last(); strings.Contains(last, "Stopped at the limit") {
+		t.Errorf("got %q for a playlist within the limit", last)
+	}
+
+	longer := "longer" + s.guildID
+	s.finishPlaylist(longer, 3)
+	s.send("alice", ">play https://www.youtube.com/playlist?list="+longer)
+	s.expect("Enqueued 2 tracks from the playlist. Stopped at the limit of 2 tracks.")
+}
+
+func TestUndoStopsPlaylist(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+	list := "undo" + s.guildID
+
+	done := make(chan struct{})
+	go func() {
+		s.send("alice", ">play https://www.youtube.com/playlist?list="+list)
+		close(done)
+	}()
+	s.expectPlaying("https://youtu.be/" + list + "1")
+	s.send("alice", ">play https://youtu.be/a")
+	s.send("alice", ">undo")
+	s.expect("Undid enqueue")
+	s.send("alice", ">undo")
+	s.expect("Undid playlist")
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the playlist kept loading after it was undone")
+	}
+	s.expectQueued()
+}
+
+func TestLikes(t *testing.T) {
+	s := newScenario(t)
+	// Likes follow users across guilds, so this one is the scenario's own.
+	user := "liker" + s.guildID
+	fake.SetVoiceState(s.guildID, user, s.voiceID)
+
+	s.send(user, ">like")
+	s.expect("Nothing is playing.")
+	s.send(user, ">likes")
+	s.expect("You haven't liked anything yet")
+
+	s.send(user, ">play https://youtu.be/a")
+	s.expectPlaying("https://youtu.be/a")
+	s.send(user, ">like")
+	s.expect("Added Title a to your likes.")
+	s.send(user, ">like")
+	s.expect("You already like Title a.")
+	s.send(user, ">skip")
+	s.send(user, ">play https://youtu.be/b")
+	s.expectPlaying("https://youtu.be/b")
+	s.send(user, ">like")
+
+	s.send(user, ">likes")
+	s.expect("Your likes (page 1/1):\n1. Title b `3:00`")
+	s.send(user, ">likes 2")
+	s.expect("Page must be between 1 and 1.")
+
+	s.send(user, ">likes play")
+	s.expect("Enqueued 2 tracks from your likes.")
+	s.expectQueued("https://youtu.be/a", "https://youtu.be/b")
+
+	s.send(user, ">unlike")
+	s.expect("Removed Title b from your likes.")
+	s.send(user, ">unlike 2")
+	s.expect("Invalid number.")
+	s.send(user, ">unlike 1")
+	s.expect("Removed Title a from your likes.")
+	s.send(user, ">likes")
+	if !strings.Contains(s.last(), "You haven't liked anything yet") {
+		t.Errorf("likes after unliking both: %q", s.last())
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := newScenario(t)
+	fake.SetVoiceState(s.guildID, "alice", s.voiceID)
+
+	s.send("alice", ">top tracks")
+	s.expect("Nothing has been played through yet.")
+	s.send("alice", ">top requesters")
+	s.expect("Nobody has queued anything yet.")
+
+	s.send("alice", ">play https://youtu.be/a")
+	s.send("alice", ">play https://youtu.be/b")
+	s.expectPlaying("https://youtu.be/a")
+	s.send("alice", ">skip")
+	s.expectPlaying("https://youtu.be/b")
+	s.waitFor("the skip to be counted", func() bool {
+		return services.Stats.Guild(s.guildID).Skips == 1
+	})
+
+	s.send("alice", ">stats")
+	s.expect("• Skipped: 1\n• Tracks queued: 2")
+	s.send("alice", ">stats me")
+	s.expect("• Tracks queued: 2\n• Of those, played through: 0, skipped: 1")
+	s.send("alice", ">top requesters")
+	s.expect("1. <@alice> — 2 tracks (0 played through, 1 skipped)")
+	s.send("alice", ">top tracks year")
+	s.expect("Usage: `>top tracks [week|month|all]`")
+}
+
+func TestScheduleAddFindsChannel(t *testing.T) {
+	s := newScenario(t)
+	t.Cleanup(func() {
+		for _, sc := range services.Schedules.List(s.guildID) {
+			services.Schedules.Remove(s.guildID, sc.ID)
+		}
+	})
+	for _, channel := range []string{"focus room", `"Focus Room"`, "<#" + s.voiceID + ">", s.voiceID} {
+		s.send(adminID, ">schedule add 0 9 * * mon-sun "+channel+" https://youtu.be/a")
+		if !strings.Contains(s.last(), "Added schedule #") {
+			t.Errorf("adding a schedule in %s: %q", channel, s.last())
+		}
+	}
+	for _, sc := range services.Schedules.List(s.guildID) {
+		if sc.VoiceChannelID != s.voiceID || sc.Source != "https://youtu.be/a" {
+			t.Errorf("schedule %+v, want %s to play in %s", sc, "https://youtu.be/a", s.voiceID)
+		}
+	}
+
+	s.send(adminID, ">schedule add 0 9 * * * lounge https://youtu.be/a")
+	s.expect("No voice channel called lounge")
+	s.send(adminID, ">schedule add 0 9 * * * focus room")
+	s.expect("No voice channel called focus")
+	s.send("alice", ">schedule add 0 9 * * * focus room https://youtu.be/a")
+	s.expect("Only server admins can change schedules")
+	if n := len(services.Schedules.List(s.guildID)); n != 4 {
+		t.Errorf("%d schedules, want 4", n)
+	}
+}
@@ -0,0 +1,79 @@
+package framework
+
+import (
+	"fmt"
+	"musicbot/audio"
+	commands "musicbot/cmd"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// positionSaveInterval is how often the playback position of playing
+// queues is written out between queue changes.
+const positionSaveInterval = 15 * time.Second
+
+// subscribePersistence saves a guild's queue on every change so it can be
+// restored after a restart.
+func subscribePersistence() {
+	services.Bus.SubscribeAll(func(ev audio.Event) {
+		switch ev.(type) {
+		case audio.QueueChanged, audio.TrackStarted, audio.TrackEnded, audio.VoiceJoined, audio.VoiceLeft:
+			saveQueue(ev.Guild())
+		}
+	})
+
+	go func() {
+		for range time.Tick(positionSaveInterval) {
+			for _, guildID := range services.QueueManager.Guilds() {
+				if services.Players.Get(guildID).State() == audio.StatePlaying {
+					saveQueue(guildID)
+				}
+			}
+		}
+	}()
+}
+
+func saveQueue(guildID string) {
+	if err := services.SaveQueue(guildID); err != nil {
+		fmt.Println("Failed to save queue for", guildID+":", err)
+	}
+}
+
+func saveAllQueues() {
+	for _, guildID := range services.QueueManager.Guilds() {
+		saveQueue(guildID)
+	}
+}
+
+// restoreSavedQueues resumes queues saved before the last shutdown in
+// guilds with auto-restore enabled, and offers >restore everywhere else.
+func restoreSavedQueues() {
+	saved, err := services.LoadSavedQueues()
+	if err != nil {
+		fmt.Println("Failed to load saved queues:", err)
+		return
+	}
+
+	for guildID, snap := range saved {
+		if snap.TextChannelID == "" {
+			continue
+		}
+
+		if !services.Settings.Get(guildID).AutoRestore {
+			client.ChannelMessageSend(snap.TextChannelID,
+				"💾 I was restarted with music still queued. Type `>restore` to pick up where we left off, or `>restore discard` to drop it.")
+			continue
+		}
+
+		m := &discordgo.MessageCreate{Message: &discordgo.Message{
+			GuildID:   guildID,
+			ChannelID: snap.TextChannelID,
+			Author:    &discordgo.User{ID: client.BotUserID()},
+		}}
+		cmd := commands.NewBotCommand(client, m, services)
+		if snap := services.TakeRestore(guildID); snap != nil {
+			cmd.RestoreSnapshot(snap)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package framework
+
+import (
+	"musicbot/schedule"
+	"time"
+)
+
+// startScheduler runs the guilds' >schedule entries in their time zones
+// for as long as the bot is up.
+func startScheduler() {
+	location := func(guildID string) *time.Location {
+		return services.Settings.Get(guildID).Location()
+	}
+	scheduler := schedule.NewScheduler(services.Schedules, schedule.SystemClock{}, location, func(sc *schedule.Schedule) {
+		go services.RunSchedule(client, sc)
+	})
+	go scheduler.Run(nil)
+}
@@ -4,7 +4,7 @@ go 1.24.4
 
 require (
 	github.com/bwmarrin/discordgo v0.29.0
-	layeh.com/gopus v0.0.0-20210501142526-1ee02d434e32
+	gopkg.in/hraban/opus.v2 v2.0.0-20230925203106-0188a62cb302
 )
 
 require (
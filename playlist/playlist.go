@@ -0,0 +1,169 @@
+package playlist
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/audio"
+	"musicbot/store"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("playlist not found")
+
+// Playlist is a user's saved list of resolved tracks. Shared playlists can
+// be loaded by anyone in the guild they were shared with.
+type Playlist struct {
+	Name      string         `json:"name"`
+	OwnerID   string         `json:"owner_id"`
+	GuildID   string         `json:"guild_id"`
+	Shared    bool           `json:"shared"`
+	Tracks    []*audio.Track `json:"tracks"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type Manager struct {
+	playlists map[string]*Playlist // ownerID/name → Playlist
+	store     *store.Store
+	sync.Mutex
+}
+
+const storeKey = "playlists"
+
+// NewManager loads every saved playlist from st.
+func NewManager(st *store.Store) (*Manager, error) {
+	m := &Manager{
+		playlists: make(map[string]*Playlist),
+		store:     st,
+	}
+	if st != nil {
+		if _, err := st.Load(storeKey, &m.playlists); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func key(ownerID, name string) string {
+	return ownerID + "/" + strings.ToLower(name)
+}
+
+// save persists every playlist. Callers must hold the lock.
+func (m *Manager) save() {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Save(storeKey, m.playlists); err != nil {
+		fmt.Println("Failed to save playlists:", err)
+	}
+}
+
+// Find returns userID's playlist called name, falling back to one shared
+// with guildID by someone else.
+func (m *Manager) Find(userID, guildID, name string) (*Playlist, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if p, ok := m.playlists[key(userID, name)]; ok {
+		return p.copy(), nil
+	}
+	for _, p := range m.playlists {
+		if p.Shared && p.GuildID == guildID && strings.EqualFold(p.Name, name) {
+			return p.copy(), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns userID's playlists and those shared with guildID, sorted by name.
+func (m *Manager) List(userID, guildID string) []*Playlist {
+	m.Lock()
+	defer m.Unlock()
+
+	var out []*Playlist
+	for _, p := range m.playlists {
+		if p.OwnerID == userID || (p.Shared && p.GuildID == guildID) {
+			out = append(out, p.copy())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name)
+	})
+	return out
+}
+
+// Save creates or replaces ownerID's playlist called name with tracks.
+func (m *Manager) Save(ownerID, guildID, name string, tracks []*audio.Track) *Playlist {
+	m.Lock()
+	defer m.Unlock()
+
+	p, ok := m.playlists[key(ownerID, name)]
+	if !ok {
+		p = &Playlist{Name: name, OwnerID: ownerID, GuildID: guildID}
+		m.playlists[key(ownerID, name)] = p
+	}
+	p.Tracks = cloneTracks(tracks)
+	p.UpdatedAt = time.Now()
+	m.save()
+	return p.copy()
+}
+
+// Add appends track to ownerID's playlist called name, creating it if needed.
+func (m *Manager) Add(ownerID, guildID, name string, track *audio.Track) *Playlist {
+	m.Lock()
+	defer m.Unlock()
+
+	p, ok := m.playlists[key(ownerID, name)]
+	if !ok {
+		p = &Playlist{Name: name, OwnerID: ownerID, GuildID: guildID}
+		m.playlists[key(ownerID, name)] = p
+	}
+	p.Tracks = append(p.Tracks, cloneTracks([]*audio.Track{track})...)
+	p.UpdatedAt = time.Now()
+	m.save()
+	return p.copy()
+}
+
+// SetShared shares ownerID's playlist with the guild it was created in.
+func (m *Manager) SetShared(ownerID, name string, shared bool) error {
+	m.Lock()
+	defer m.Unlock()
+
+	p, ok := m.playlists[key(ownerID, name)]
+	if !ok {
+		return ErrNotFound
+	}
+	p.Shared = shared
+	m.save()
+	return nil
+}
+
+func (m *Manager) Delete(ownerID, name string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.playlists[key(ownerID, name)]; !ok {
+		return ErrNotFound
+	}
+	delete(m.playlists, key(ownerID, name))
+	m.save()
+	return nil
+}
+
+func (p *Playlist) copy() *Playlist {
+	c := *p
+	c.Tracks = cloneTracks(p.Tracks)
+	return &c
+}
+
+// cloneTracks cleans each of tracks, so edits to the queue never leak into
+// a saved playlist.
+func cloneTracks(tracks []*audio.Track) []*audio.Track {
+	out := make([]*audio.Track, len(tracks))
+	for i, t := range tracks {
+		out[i] = t.Clean()
+	}
+	return out
+}
@@ -0,0 +1,34 @@
+package playlist
+
+import (
+	"musicbot/audio"
+	"testing"
+	"time"
+)
+
+func TestSavedTracksAreClean(t *testing.T) {
+	m, err := NewManager(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued := &audio.Track{
+		URL:           "https://youtu.be/a",
+		Title:         "A",
+		Duration:      "3:00",
+		RequesterID:   "alice",
+		RequesterName: "Alice",
+		EnqueuedAt:    time.Now(),
+		Autoplay:      true,
+		Offset:        time.Minute,
+	}
+	m.Save("alice", "guild", "mix", []*audio.Track{queued})
+
+	p, err := m.Find("alice", "guild", "mix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := audio.Track{URL: "https://youtu.be/a", Title: "A", Duration: "3:00"}
+	if len(p.Tracks) != 1 || *p.Tracks[0] != want {
+		t.Errorf("saved %+v, want only %+v", p.Tracks, want)
+	}
+}
@@ -0,0 +1,89 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, tt := range []struct {
+		expr string
+		ok   bool
+	}{
+		{"* * * * *", true},
+		{"*/15 9-17 * * mon-fri", true},
+		{"0 9 * * mon-sun", true},
+		{"0 9 * * sat-sun", true},
+		{"0 9 * * 1-7", true},
+		{"0 9 1,15 jan-mar *", true},
+		{"0 9 * * fri-mon", false},
+		{"0 9 * * 5-1", false},
+		{"0 9 * * *  *", false},
+		{"0 9 * *", false},
+		{"60 * * * *", false},
+		{"*/0 * * * *", false},
+		{"0 9 * foo *", false},
+	} {
+		if _, err := ParseCron(tt.expr); (err == nil) != tt.ok {
+			t.Errorf("ParseCron(%q) error = %v", tt.expr, err)
+		}
+	}
+}
+
+func TestCronWeekdayRanges(t *testing.T) {
+	monday := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		expr string
+		days string // Mon to Sun
+	}{
+		{"0 9 * * mon-sun", "MTWTFSS"},
+		{"0 9 * * sat-sun", ".....SS"},
+		{"0 9 * * mon-fri", "MTWTF.."},
+		{"0 9 * * 0", "......S"},
+		{"0 9 * * 7", "......S"},
+		{"0 9 * * sun-tue", "MT....S"},
+	} {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		got := []byte(".......")
+		for i := range got {
+			if c.matchDay(monday.AddDate(0, 0, i)) {
+				got[i] = "MTWTFSS"[i]
+			}
+		}
+		if string(got) != tt.days {
+			t.Errorf("%q runs on %s, want %s", tt.expr, got, tt.days)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+	for _, tt := range []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"30 9 * * *", time.Date(2024, 6, 3, 9, 29, 59, 0, time.UTC), time.Date(2024, 6, 3, 9, 30, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2024, 6, 3, 9, 30, 0, 0, time.UTC), time.Date(2024, 6, 4, 9, 30, 0, 0, time.UTC)},
+		// Either day matches when both are restricted.
+		{"0 0 13 * fri", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon-sun", time.Date(2024, 6, 8, 13, 0, 0, 0, time.UTC), time.Date(2024, 6, 9, 12, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		// 1:30 doesn't exist on the day the clocks go forward.
+		{"30 1 * * *", time.Date(2024, 3, 30, 12, 0, 0, 0, london), time.Date(2024, 4, 1, 1, 30, 0, 0, london)},
+	} {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q after %s: got %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/store"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("schedule not found")
+
+// Schedule plays Source in a voice channel whenever Cron matches, in the
+// guild's time zone. Source is a playlist name or a URL.
+type Schedule struct {
+	ID             int       `json:"id"`
+	GuildID        string    `json:"guild_id"`
+	Cron           string    `json:"cron"`
+	VoiceChannelID string    `json:"voice_channel_id"`
+	TextChannelID  string    `json:"text_channel_id"`
+	Source         string    `json:"source"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	cron *Cron
+}
+
+// Next returns when the schedule next runs after t, in loc.
+func (s *Schedule) Next(t time.Time, loc *time.Location) time.Time {
+	return s.cron.Next(t.In(loc))
+}
+
+type Manager struct {
+	schedules map[int]*Schedule // ID → Schedule
+	nextID    int
+	store     *store.Store
+	sync.Mutex
+}
+
+const storeKey = "schedules"
+
+// NewManager loads every saved schedule from st. A nil store keeps
+// schedules in memory only.
+func NewManager(st *store.Store) (*Manager, error) {
+	m := &Manager{
+		schedules: make(map[int]*Schedule),
+		nextID:    1,
+		store:     st,
+	}
+	if st == nil {
+		return m, nil
+	}
+
+	var saved []*Schedule
+	if _, err := st.Load(storeKey, &saved); err != nil {
+		return nil, err
+	}
+	for _, s := range saved {
+		cron, err := ParseCron(s.Cron)
+		if err != nil {
+			fmt.Println("Dropping schedule", s.ID, "with a bad cron expression:", err)
+			continue
+		}
+		s.cron = cron
+		m.schedules[s.ID] = s
+		if s.ID >= m.nextID {
+			m.nextID = s.ID + 1
+		}
+	}
+	return m, nil
+}
+
+// save persists every schedule. Callers must hold the lock.
+func (m *Manager) save() {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Save(storeKey, m.sorted(func(*Schedule) bool { return true })); err != nil {
+		fmt.Println("Failed to save schedules:", err)
+	}
+}
+
+// sorted returns copies of the schedules keep accepts, by ID. Callers
+// must hold the lock.
+func (m *Manager) sorted(keep func(*Schedule) bool) []*Schedule {
+	var out []*Schedule
+	for _, s := range m.schedules {
+		if keep(s) {
+			c := *s
+			out = append(out, &c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Add validates s.Cron and saves s under a new ID.
+func (m *Manager) Add(s Schedule) (*Schedule, error) {
+	cron, err := ParseCron(s.Cron)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	s.ID = m.nextID
+	s.cron = cron
+	m.nextID++
+	m.schedules[s.ID] = &s
+	m.save()
+
+	c := s
+	return &c, nil
+}
+
+// List returns guildID's schedules by ID.
+func (m *Manager) List(guildID string) []*Schedule {
+	m.Lock()
+	defer m.Unlock()
+	return m.sorted(func(s *Schedule) bool { return s.GuildID == guildID })
+}
+
+// All returns every guild's schedules by ID.
+func (m *Manager) All() []*Schedule {
+	m.Lock()
+	defer m.Unlock()
+	return m.sorted(func(*Schedule) bool { return true })
+}
+
+// Remove deletes guildID's schedule with id.
+func (m *Manager) Remove(guildID string, id int) error {
+	m.Lock()
+	defer m.Unlock()
+
+	s, ok := m.schedules[id]
+	if !ok || s.GuildID != guildID {
+		return ErrNotFound
+	}
+	delete(m.schedules, id)
+	m.save()
+	return nil
+}
@@ -0,0 +1,84 @@
+package schedule
+
+import "time"
+
+// Clock tells the time and waits. The scheduler takes one so it can be
+// driven by a fake clock instead of the wall clock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time                         { return time.Now() }
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// maxWait caps how long the scheduler sleeps, so schedules added or time
+// zones changed in the meantime are picked up.
+const maxWait = time.Minute
+
+// Scheduler runs schedules as they come due.
+type Scheduler struct {
+	schedules *Manager
+	clock     Clock
+	location  func(guildID string) *time.Location
+	run       func(s *Schedule)
+	last      time.Time // schedules are due up to here
+}
+
+// NewScheduler runs schedules from schedules with run, reading each
+// guild's time zone from location.
+func NewScheduler(schedules *Manager, clock Clock, location func(guildID string) *time.Location, run func(s *Schedule)) *Scheduler {
+	return &Scheduler{
+		schedules: schedules,
+		clock:     clock,
+		location:  location,
+		run:       run,
+		last:      clock.Now(),
+	}
+}
+
+// Run runs schedules as they come due until stop is closed. Times missed
+// while the bot was down are not caught up on.
+func (sc *Scheduler) Run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-sc.clock.After(sc.wait()):
+			sc.Tick(sc.clock.Now())
+		}
+	}
+}
+
+// Tick runs every schedule due since the previous tick, up to now, and
+// returns them. A schedule due several times in that span runs once.
+func (sc *Scheduler) Tick(now time.Time) []*Schedule {
+	var due []*Schedule
+	for _, s := range sc.schedules.All() {
+		next := s.Next(sc.last, sc.location(s.GuildID))
+		if !next.IsZero() && !next.After(now) {
+			due = append(due, s)
+			sc.run(s)
+		}
+	}
+	sc.last = now
+	return due
+}
+
+// wait returns how long until the next schedule is due, up to maxWait.
+func (sc *Scheduler) wait() time.Duration {
+	now := sc.clock.Now()
+	wait := maxWait
+	for _, s := range sc.schedules.All() {
+		next := s.Next(sc.last, sc.location(s.GuildID))
+		if !next.IsZero() && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
@@ -0,0 +1,129 @@
+package schedule
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when a test advances it. Each wait the scheduler
+// starts is handed to the test on waits.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits chan fakeWait
+}
+
+type fakeWait struct {
+	d  time.Duration
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, waits: make(chan fakeWait)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.waits <- fakeWait{d: d, ch: ch}
+	return ch
+}
+
+// expectWait waits for the scheduler to start waiting, checks for how
+// long, and lets that time pass.
+func (c *fakeClock) expectWait(t *testing.T, want time.Duration) {
+	t.Helper()
+	select {
+	case w := <-c.waits:
+		if w.d != want {
+			t.Errorf("waited %s, want %s", w.d, want)
+		}
+		c.mu.Lock()
+		c.now = c.now.Add(w.d)
+		now := c.now
+		c.mu.Unlock()
+		w.ch <- now
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scheduler never waited")
+	}
+}
+
+func at(hour, minute, second int) time.Time {
+	return time.Date(2024, 6, 3, hour, minute, second, 0, time.UTC)
+}
+
+func utc(string) *time.Location { return time.UTC }
+
+func TestSchedulerRun(t *testing.T) {
+	schedules, _ := NewManager(nil)
+	schedules.Add(Schedule{GuildID: "guild", Cron: "0 9 * * *", Source: "morning"})
+	clock := newFakeClock(at(8, 58, 30))
+
+	ran := make(chan string, 10)
+	sc := NewScheduler(schedules, clock, utc, func(s *Schedule) { ran <- s.Source })
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		sc.Run(stop)
+		close(done)
+	}()
+
+	// Waits are capped at a minute, then run up to the schedule.
+	clock.expectWait(t, time.Minute)
+	clock.expectWait(t, 30*time.Second)
+	select {
+	case source := <-ran:
+		if source != "morning" {
+			t.Errorf("ran %s", source)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the schedule didn't run at 9:00")
+	}
+	clock.expectWait(t, time.Minute)
+	if len(ran) != 0 {
+		t.Errorf("ran %s again", <-ran)
+	}
+
+	close(stop)
+	select {
+	case <-clock.waits:
+	case <-done:
+	}
+}
+
+func TestSchedulerTick(t *testing.T) {
+	schedules, _ := NewManager(nil)
+	schedules.Add(Schedule{GuildID: "utc", Cron: "*/10 * * * *", Source: "every ten"})
+	schedules.Add(Schedule{GuildID: "tokyo", Cron: "0 18 * * *", Source: "tokyo evening"})
+	tokyo := time.FixedZone("JST", 9*60*60)
+	location := func(guildID string) *time.Location {
+		if guildID == "tokyo" {
+			return tokyo
+		}
+		return time.UTC
+	}
+
+	var ran []string
+	sc := NewScheduler(schedules, newFakeClock(at(8, 55, 0)), location, func(s *Schedule) { ran = append(ran, s.Source) })
+
+	// Due twice since the last tick, but run once; 18:00 in Tokyo is 9:00 UTC.
+	due := sc.Tick(at(9, 15, 0))
+	if len(due) != 2 || len(ran) != 2 {
+		t.Errorf("ran %v, want every ten once and tokyo evening", ran)
+	}
+	ran = nil
+	sc.Tick(at(9, 19, 0))
+	if len(ran) != 0 {
+		t.Errorf("ran %v with nothing due", ran)
+	}
+	sc.Tick(at(9, 20, 0))
+	if len(ran) != 1 || ran[0] != "every ten" {
+		t.Errorf("ran %v at 9:20, want every ten", ran)
+	}
+}
@@ -0,0 +1,177 @@
+package settings
+
+import (
+	"fmt"
+	"musicbot/store"
+	"sync"
+	"time"
+)
+
+// QualityOverrides are encoder values set by admins with >quality.
+// Nil fields fall back to what is derived from the voice channel.
+type QualityOverrides struct {
+	Bitrate    *int  `json:"bitrate,omitempty"`
+	Complexity *int  `json:"complexity,omitempty"`
+	FEC        *bool `json:"fec,omitempty"`
+	PacketLoss *int  `json:"packet_loss,omitempty"`
+}
+
+// DuplicatePolicy decides whether a track can be queued again.
+type DuplicatePolicy string
+
+const (
+	DuplicatesAllow DuplicatePolicy = ""
+	// DuplicatesReject turns away tracks that are queued, playing or were
+	// played recently, and the same track requested twice at once, e.g.
+	// from a playlist.
+	DuplicatesReject DuplicatePolicy = "reject"
+	// DuplicatesRejectQueued only turns away tracks that are queued or playing.
+	DuplicatesRejectQueued DuplicatePolicy = "reject-queued"
+)
+
+// Limits cap what non-DJs can queue. Zero means no limit.
+type Limits struct {
+	MaxQueue       int             `json:"max_queue,omitempty"`
+	MaxPerUser     int             `json:"max_per_user,omitempty"`
+	MaxTrackLength time.Duration   `json:"max_track_length,omitempty"`
+	MaxPlaylist    int             `json:"max_playlist,omitempty"`
+	Duplicates     DuplicatePolicy `json:"duplicates,omitempty"`
+}
+
+type GuildSettings struct {
+	Quality QualityOverrides `json:"quality"`
+	Limits  Limits           `json:"limits"`
+
+	// AutoRestore rejoins voice and resumes the saved queue on startup
+	// instead of asking first.
+	AutoRestore bool `json:"auto_restore"`
+
+	// FairQueue interleaves the queue by requester.
+	FairQueue bool `json:"fair_queue"`
+
+	// DJRoleID is the role whose members can manage the queue like admins.
+	DJRoleID string `json:"dj_role_id,omitempty"`
+
+	// Autoplay picks related tracks when the queue runs dry. It will not
+	// pick anything among the last AutoplayWindow played tracks (nil means
+	// DEFAULT_AUTOPLAY_WINDOW, 0 means no window).
+	Autoplay       bool `json:"autoplay"`
+	AutoplayWindow *int `json:"autoplay_window,omitempty"`
+
+	// VoteSkip makes >skip a vote among the listeners, passing once
+	// VoteSkipPercent of them agree (0 means DEFAULT_VOTE_SKIP_PERCENT).
+	VoteSkip        bool `json:"vote_skip"`
+	VoteSkipPercent int  `json:"vote_skip_percent,omitempty"`
+
+	// PlaylistCap is the most entries of one playlist anyone, DJs
+	// included, can queue at once (0 means DEFAULT_PLAYLIST_CAP).
+	PlaylistCap int `json:"playlist_cap,omitempty"`
+
+	// Timezone is the IANA zone schedules run in, e.g. "Europe/Berlin".
+	// Empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// DEFAULT_AUTOPLAY_WINDOW is how many recently played tracks autoplay
+// avoids repeating unless the guild sets its own window.
+const DEFAULT_AUTOPLAY_WINDOW = 50
+
+// RepeatWindow is the effective autoplay repeat window.
+func (gs GuildSettings) RepeatWindow() int {
+	if gs.AutoplayWindow == nil {
+		return DEFAULT_AUTOPLAY_WINDOW
+	}
+	return *gs.AutoplayWindow
+}
+
+// DEFAULT_VOTE_SKIP_PERCENT is the share of listeners a vote skip needs
+// unless the guild sets its own.
+const DEFAULT_VOTE_SKIP_PERCENT = 50
+
+// SkipPercent is the effective vote skip threshold.
+func (gs GuildSettings) SkipPercent() int {
+	if gs.VoteSkipPercent <= 0 {
+		return DEFAULT_VOTE_SKIP_PERCENT
+	}
+	return gs.VoteSkipPercent
+}
+
+// DEFAULT_PLAYLIST_CAP keeps huge playlists and endless mixes from
+// flooding the queue unless the guild sets its own cap.
+const DEFAULT_PLAYLIST_CAP = 500
+
+// ExpandCap is the effective playlist cap.
+func (gs GuildSettings) ExpandCap() int {
+	if gs.PlaylistCap <= 0 {
+		return DEFAULT_PLAYLIST_CAP
+	}
+	return gs.PlaylistCap
+}
+
+// Location is the guild's time zone, falling back to UTC if it is unset
+// or no longer known.
+func (gs GuildSettings) Location() *time.Location {
+	loc, err := time.LoadLocation(gs.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+type Manager struct {
+	mu     sync.Mutex
+	guilds map[string]*GuildSettings // guildID → settings
+	store  *store.Store
+}
+
+// NewManager returns a manager persisting to st. A nil store keeps
+// settings in memory only.
+func NewManager(st *store.Store) *Manager {
+	return &Manager{
+		guilds: make(map[string]*GuildSettings),
+		store:  st,
+	}
+}
+
+func key(guildID string) string {
+	return "settings/" + guildID
+}
+
+// load returns the guild's settings, reading them from the store on first
+// use. Callers must hold mu for writing.
+func (m *Manager) load(guildID string) *GuildSettings {
+	if gs, ok := m.guilds[guildID]; ok {
+		return gs
+	}
+	gs := &GuildSettings{}
+	if m.store != nil {
+		if _, err := m.store.Load(key(guildID), gs); err != nil {
+			fmt.Println("Failed to load settings for", guildID+":", err)
+		}
+	}
+	m.guilds[guildID] = gs
+	return gs
+}
+
+// Get returns a copy of the guild's settings.
+func (m *Manager) Get(guildID string) GuildSettings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *m.load(guildID)
+}
+
+// Update applies fn to the guild's settings under the manager lock and
+// persists the result.
+func (m *Manager) Update(guildID string, fn func(*GuildSettings)) GuildSettings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	gs := m.load(guildID)
+	fn(gs)
+	if m.store != nil {
+		if err := m.store.Save(key(guildID), gs); err != nil {
+			fmt.Println("Failed to save settings for", guildID+":", err)
+		}
+	}
+	return *gs
+}
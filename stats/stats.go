@@ -0,0 +1,246 @@
+package stats
+
+import (
+	"fmt"
+	"musicbot/audio"
+	"musicbot/store"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MAX_DAYS is how many days of daily play counts are kept per track, for
+// the weekly and monthly charts.
+const MAX_DAYS = 31
+
+const dayFormat = "2006-01-02"
+
+// Counts are what is counted for a guild, a track or a user. Plays are
+// tracks played to the end; skips are counted apart so a track people
+// skip doesn't look popular.
+type Counts struct {
+	Plays    int           `json:"plays"`
+	Skips    int           `json:"skips"`
+	Requests int           `json:"requests"`
+	Listened time.Duration `json:"listened"`
+}
+
+type TrackStats struct {
+	Counts
+	Title string         `json:"title"`
+	URL   string         `json:"url"`
+	Daily map[string]int `json:"daily,omitempty"` // day → plays, for the last MAX_DAYS days
+}
+
+// GuildStats are a guild's totals and its per-track and per-user counts.
+// A user's plays, skips and requests are of the tracks they queued, and
+// their listening time is time spent in voice while a track played.
+type GuildStats struct {
+	Counts
+	Tracks map[string]*TrackStats `json:"tracks"` // Track.Key() → stats
+	Users  map[string]*Counts     `json:"users"`  // userID → stats
+}
+
+// Ranked is a track or user with its counts, for the charts.
+type Ranked struct {
+	ID    string // Track.Key() or userID
+	Title string // tracks only
+	URL   string // tracks only
+	Counts
+}
+
+type Manager struct {
+	guilds map[string]*GuildStats // guildID → stats
+	store  *store.Store
+	sync.Mutex
+}
+
+// NewManager returns a manager persisting to st. A nil store keeps stats
+// in memory only.
+func NewManager(st *store.Store) *Manager {
+	return &Manager{
+		guilds: make(map[string]*GuildStats),
+		store:  st,
+	}
+}
+
+func key(guildID string) string {
+	return "stats/" + guildID
+}
+
+// get returns the guild's stats, loading them on first use. Callers must
+// hold the lock.
+func (m *Manager) get(guildID string) *GuildStats {
+	if gs, ok := m.guilds[guildID]; ok {
+		return gs
+	}
+	gs := &GuildStats{}
+	if m.store != nil {
+		if _, err := m.store.Load(key(guildID), gs); err != nil {
+			fmt.Println("Failed to load stats for", guildID+":", err)
+		}
+	}
+	if gs.Tracks == nil {
+		gs.Tracks = make(map[string]*TrackStats)
+	}
+	if gs.Users == nil {
+		gs.Users = make(map[string]*Counts)
+	}
+	m.guilds[guildID] = gs
+	return gs
+}
+
+// save persists the guild's stats. Callers must hold the lock.
+func (m *Manager) save(guildID string) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Save(key(guildID), m.guilds[guildID]); err != nil {
+		fmt.Println("Failed to save stats for", guildID+":", err)
+	}
+}
+
+func (gs *GuildStats) track(t *audio.Track) *TrackStats {
+	ts, ok := gs.Tracks[t.Key()]
+	if !ok {
+		ts = &TrackStats{}
+		gs.Tracks[t.Key()] = ts
+	}
+	ts.Title, ts.URL = t.Title, t.URL
+	return ts
+}
+
+func (gs *GuildStats) user(userID string) *Counts {
+	c, ok := gs.Users[userID]
+	if !ok {
+		c = &Counts{}
+		gs.Users[userID] = c
+	}
+	return c
+}
+
+// RecordRequests counts userID queuing tracks.
+func (m *Manager) RecordRequests(guildID, userID string, tracks []*audio.Track) {
+	m.Lock()
+	defer m.Unlock()
+
+	gs := m.get(guildID)
+	for _, t := range tracks {
+		gs.Requests++
+		gs.track(t).Requests++
+		gs.user(userID).Requests++
+	}
+	m.save(guildID)
+}
+
+// RecordPlay counts t having played for played, either to the end or
+// until it was skipped, while listeners were in voice. Tracks that were
+// stopped or interrupted only add listening time.
+func (m *Manager) RecordPlay(guildID string, t *audio.Track, reason audio.EndReason, played time.Duration, listeners []string, at time.Time) {
+	m.Lock()
+	defer m.Unlock()
+
+	gs := m.get(guildID)
+	ts := gs.track(t)
+	counted := []*Counts{&gs.Counts, &ts.Counts}
+	if t.RequesterID != "" {
+		counted = append(counted, gs.user(t.RequesterID))
+	}
+	for _, c := range counted {
+		switch reason {
+		case audio.EndFinished:
+			c.Plays++
+		case audio.EndSkipped:
+			c.Skips++
+		}
+	}
+
+	gs.Listened += played
+	ts.Listened += played
+	for _, userID := range listeners {
+		gs.user(userID).Listened += played
+	}
+
+	if reason == audio.EndFinished {
+		if ts.Daily == nil {
+			ts.Daily = make(map[string]int)
+		}
+		ts.Daily[at.Format(dayFormat)]++
+		oldest := at.AddDate(0, 0, -MAX_DAYS).Format(dayFormat)
+		for day := range ts.Daily {
+			if day < oldest {
+				delete(ts.Daily, day)
+			}
+		}
+	}
+	m.save(guildID)
+}
+
+// Guild returns the guild's totals.
+func (m *Manager) Guild(guildID string) Counts {
+	m.Lock()
+	defer m.Unlock()
+	return m.get(guildID).Counts
+}
+
+// User returns userID's counts in the guild.
+func (m *Manager) User(guildID, userID string) Counts {
+	m.Lock()
+	defer m.Unlock()
+
+	if c, ok := m.get(guildID).Users[userID]; ok {
+		return *c
+	}
+	return Counts{}
+}
+
+// TopTracks returns the n most played tracks, counting only plays in the
+// last days days up to now when days is above 0. Skips don't count.
+func (m *Manager) TopTracks(guildID string, days int, now time.Time, n int) []Ranked {
+	m.Lock()
+	defer m.Unlock()
+
+	since := now.AddDate(0, 0, -days+1).Format(dayFormat)
+	var out []Ranked
+	for k, ts := range m.get(guildID).Tracks {
+		r := Ranked{ID: k, Title: ts.Title, URL: ts.URL, Counts: ts.Counts}
+		if days > 0 {
+			r.Plays = 0
+			for day, plays := range ts.Daily {
+				if day >= since {
+					r.Plays += plays
+				}
+			}
+		}
+		if r.Plays > 0 {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Plays != out[j].Plays {
+			return out[i].Plays > out[j].Plays
+		}
+		return out[i].Title < out[j].Title
+	})
+	return out[:min(n, len(out))]
+}
+
+// TopRequesters returns the n users who queued the most tracks.
+func (m *Manager) TopRequesters(guildID string, n int) []Ranked {
+	m.Lock()
+	defer m.Unlock()
+
+	var out []Ranked
+	for userID, c := range m.get(guildID).Users {
+		if c.Requests > 0 {
+			out = append(out, Ranked{ID: userID, Counts: *c})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Requests != out[j].Requests {
+			return out[i].Requests > out[j].Requests
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out[:min(n, len(out))]
+}
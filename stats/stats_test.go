@@ -0,0 +1,133 @@
+package stats
+
+import (
+	"musicbot/audio"
+	"musicbot/store"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var day = time.Date(2026, 3, 15, 20, 0, 0, 0, time.UTC)
+
+func track(id, requesterID string) *audio.Track {
+	return &audio.Track{URL: "https://youtu.be/" + id, Title: "Title " + id, RequesterID: requesterID}
+}
+
+func TestRecordPlay(t *testing.T) {
+	m := NewManager(nil)
+	m.RecordRequests("guild", "alice", []*audio.Track{track("a", "alice"), track("b", "alice")})
+
+	for _, reason := range []audio.EndReason{audio.EndFinished, audio.EndFinished, audio.EndSkipped, audio.EndStopped, audio.EndInterrupted} {
+		m.RecordPlay("guild", track("a", "alice"), reason, time.Minute, []string{"bob", "carol"}, day)
+	}
+	// Another link to the same video counts towards the same track.
+	m.RecordPlay("guild", &audio.Track{URL: "https://www.youtube.com/watch?v=a", Title: "Title a"}, audio.EndFinished, time.Minute, []string{"bob"}, day)
+
+	if got, want := m.Guild("guild"), (Counts{Plays: 3, Skips: 1, Requests: 2, Listened: 6 * time.Minute}); got != want {
+		t.Errorf("guild counts = %+v, want %+v", got, want)
+	}
+	// Plays and skips count towards whoever queued the track; listening
+	// time towards whoever was in voice.
+	if got, want := m.User("guild", "alice"), (Counts{Plays: 2, Skips: 1, Requests: 2}); got != want {
+		t.Errorf("alice's counts = %+v, want %+v", got, want)
+	}
+	if got, want := m.User("guild", "bob"), (Counts{Listened: 6 * time.Minute}); got != want {
+		t.Errorf("bob's counts = %+v, want %+v", got, want)
+	}
+	if got := m.User("other", "alice"); got != (Counts{}) {
+		t.Errorf("alice's counts in another guild = %+v", got)
+	}
+
+	top := m.TopTracks("guild", 0, day, 10)
+	if len(top) != 1 || top[0].ID != "youtube:a" || top[0].Plays != 3 || top[0].Skips != 1 {
+		t.Errorf("top tracks = %+v, want a with 3 plays and 1 skip", top)
+	}
+}
+
+func TestTopTracksByPeriod(t *testing.T) {
+	m := NewManager(nil)
+	play := func(id string, daysAgo, times int) {
+		for range times {
+			m.RecordPlay("guild", track(id, ""), audio.EndFinished, 0, nil, day.AddDate(0, 0, -daysAgo))
+		}
+	}
+	play("old", 20, 5)
+	play("recent", 3, 2)
+	play("today", 0, 1)
+	m.RecordPlay("guild", track("skipped", ""), audio.EndSkipped, 0, nil, day)
+
+	for _, tt := range []struct {
+		days int
+		n    int
+		want []string
+	}{
+		{days: 0, n: 10, want: []string{"youtube:old", "youtube:recent", "youtube:today"}},
+		{days: 30, n: 10, want: []string{"youtube:old", "youtube:recent", "youtube:today"}},
+		{days: 7, n: 10, want: []string{"youtube:recent", "youtube:today"}},
+		{days: 1, n: 10, want: []string{"youtube:today"}},
+		{days: 0, n: 2, want: []string{"youtube:old", "youtube:recent"}},
+	} {
+		var got []string
+		for _, r := range m.TopTracks("guild", tt.days, day, tt.n) {
+			got = append(got, r.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("top %d over %d days = %v, want %v", tt.n, tt.days, got, tt.want)
+		}
+	}
+
+	// Days past MAX_DAYS are dropped as new plays come in, but all-time
+	// plays keep them.
+	m.RecordPlay("guild", track("old", ""), audio.EndFinished, 0, nil, day.AddDate(0, 0, 20))
+	for _, r := range m.TopTracks("guild", 30, day.AddDate(0, 0, 20), 10) {
+		if r.ID == "youtube:old" && r.Plays != 1 {
+			t.Errorf("old has %d plays over the last 30 days, 20 days on, want 1", r.Plays)
+		}
+	}
+	if top := m.TopTracks("guild", 0, day, 1); top[0].Plays != 6 {
+		t.Errorf("all-time plays = %d, want 6", top[0].Plays)
+	}
+}
+
+func TestTopRequesters(t *testing.T) {
+	m := NewManager(nil)
+	m.RecordRequests("guild", "carol", []*audio.Track{track("a", "carol")})
+	m.RecordRequests("guild", "bob", []*audio.Track{track("b", "bob"), track("c", "bob")})
+	m.RecordRequests("guild", "alice", []*audio.Track{track("d", "alice"), track("e", "alice")})
+	// Listening alone doesn't make a requester.
+	m.RecordPlay("guild", track("a", "carol"), audio.EndFinished, time.Minute, []string{"dave"}, day)
+
+	var got []string
+	for _, r := range m.TopRequesters("guild", 10) {
+		got = append(got, r.ID)
+	}
+	if want := []string{"alice", "bob", "carol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("top requesters = %v, want %v", got, want)
+	}
+	if top := m.TopRequesters("guild", 1); len(top) != 1 || top[0].ID != "alice" {
+		t.Errorf("top requester = %+v, want alice", top)
+	}
+}
+
+func TestStatsPersist(t *testing.T) {
+	dir := t.TempDir()
+	st, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(st)
+	m.RecordRequests("guild", "alice", []*audio.Track{track("a", "alice")})
+	m.RecordPlay("guild", track("a", "alice"), audio.EndFinished, time.Minute, []string{"bob"}, day)
+
+	if st, err = store.Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	m = NewManager(st)
+	if got, want := m.Guild("guild"), (Counts{Plays: 1, Requests: 1, Listened: time.Minute}); got != want {
+		t.Errorf("reloaded guild counts = %+v, want %+v", got, want)
+	}
+	if top := m.TopTracks("guild", 7, day, 1); len(top) != 1 || top[0].Title != "Title a" {
+		t.Errorf("reloaded weekly top = %+v, want Title a", top)
+	}
+}
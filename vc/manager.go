@@ -1,34 +1,44 @@
 package vc
 
 import (
+	"musicbot/audio"
+	"musicbot/discord"
 	"sync"
-
-	"github.com/bwmarrin/discordgo"
 )
 
+// Voice is the bot's voice connection in a guild.
+type Voice struct {
+	ChannelID string
+	Sink      audio.AudioSink
+}
+
 type VoiceManager struct {
 	mu          sync.RWMutex
-	connections map[string]*discordgo.VoiceConnection // guildID → VC
+	connections map[string]*Voice // guildID → VC
+	bus         *audio.EventBus
 }
 
-func NewVoiceManager() *VoiceManager {
+func NewVoiceManager(bus *audio.EventBus) *VoiceManager {
 	return &VoiceManager{
-		connections: make(map[string]*discordgo.VoiceConnection),
+		connections: make(map[string]*Voice),
+		bus:         bus,
 	}
 }
 
 // Join joins the voice channel and stores the connection.
-func (vm *VoiceManager) Join(s *discordgo.Session, guildID, channelID string) (*discordgo.VoiceConnection, error) {
-	vc, err := s.ChannelVoiceJoin(guildID, channelID, false, true)
+func (vm *VoiceManager) Join(s discord.VoiceJoiner, guildID, channelID string) (*Voice, error) {
+	sink, err := s.JoinVoice(guildID, channelID)
 	if err != nil {
 		return nil, err
 	}
 
+	voice := &Voice{ChannelID: channelID, Sink: sink}
 	vm.mu.Lock()
-	vm.connections[guildID] = vc
+	vm.connections[guildID] = voice
 	vm.mu.Unlock()
 
-	return vc, nil
+	vm.bus.Publish(audio.VoiceJoined{GuildID: guildID, ChannelID: channelID})
+	return voice, nil
 }
 
 // Leave disconnects and removes the VC.
@@ -36,22 +46,23 @@ func (vm *VoiceManager) Leave(guildID string) error {
 	vm.mu.Lock()
 	defer vm.mu.Unlock()
 
-	vc, ok := vm.connections[guildID]
+	voice, ok := vm.connections[guildID]
 	if !ok {
 		return nil // nothing to disconnect
 	}
 
-	err := vc.Disconnect()
+	err := voice.Sink.Close()
 	delete(vm.connections, guildID)
 
+	vm.bus.Publish(audio.VoiceLeft{GuildID: guildID})
 	return err
 }
 
 // Get returns the VC for the guild, if it exists.
-func (vm *VoiceManager) Get(guildID string) (*discordgo.VoiceConnection, bool) {
+func (vm *VoiceManager) Get(guildID string) (*Voice, bool) {
 	vm.mu.RLock()
 	defer vm.mu.RUnlock()
 
-	vc, ok := vm.connections[guildID]
-	return vc, ok
+	voice, ok := vm.connections[guildID]
+	return voice, ok
 }